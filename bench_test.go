@@ -0,0 +1,57 @@
+package ginta
+
+import (
+	"strconv"
+	"testing"
+)
+
+const benchmarkKeys = 1000
+
+func benchmarkKey(i int) string {
+	i %= benchmarkKeys
+	return "bundle" + strconv.Itoa(i%10) + ":key" + strconv.Itoa(i)
+}
+
+// a catalog with a regional locale falling back to its language, which defines all resources
+func benchmarkCatalog(b *testing.B) *Catalog {
+	vals := make(map[string]string)
+	for i := 0; i < benchmarkKeys; i++ {
+		vals[benchmarkKey(i)] = "value" + strconv.Itoa(i)
+	}
+
+	c := NewCatalog()
+	c.Register(&mockProviderMap{"bl", vals})
+	c.Register(mockProviderEmpty("bl-XY"))
+	if err := c.Activate("bl-XY"); err != nil {
+		b.Fatal(err)
+	}
+
+	return c
+}
+
+// The full public lookup path: canonicalization, the fallback chain, activation and the snapshot lookup
+func BenchmarkGetResource(b *testing.B) {
+	c := benchmarkCatalog(b)
+	defer c.Close()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetResource("bl-XY", benchmarkKey(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetResourceParallel(b *testing.B) {
+	c := benchmarkCatalog(b)
+	defer c.Close()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := c.GetResource("bl-XY", benchmarkKey(i)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
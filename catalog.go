@@ -0,0 +1,606 @@
+package ginta
+
+import (
+	"context"
+	"errors"
+	sysfmt "fmt"
+	types "github.com/beatgammit/ginta/common"
+	"github.com/beatgammit/ginta/internal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	A catalog is an independent set of languages, together with the providers they were
+	loaded from, their fallback chains and a registry of message formats. Most applications
+	need only one catalog, and use the DefaultCatalog through the package-level functions and
+	the methods of Locale. Separate catalogs allow subsystems of one binary (or individual
+	tests) to keep separate translation sets.
+*/
+type Catalog struct {
+	universe *internal.Universe
+
+	registrationLock sync.Mutex
+	registrations    []*registration
+
+	fallbackLock sync.RWMutex
+	fallbacks    map[Locale][]Locale
+
+	// holds the current map[Locale]*resolution, keyed by the locales as given to lookups. Replaced
+	// when a locale is first looked up, and emptied when fallbacks or providers change
+	resolutions     atomic.Value
+	resolutionLock  sync.Mutex
+	resolutionEpoch int
+
+	formatLock sync.RWMutex
+	formats    map[string]interface{}
+
+	// holds the current MissHandler, which may be nil
+	missHandler atomic.Value
+
+	recordingLock sync.Mutex
+	recording     *recording
+}
+
+// a provider added to the catalog. Its address identifies the provider within the universe
+type registration struct {
+	provider LanguageProvider
+}
+
+/*
+	The catalog used by the package-level functions, and the methods of Locale
+*/
+var DefaultCatalog = NewCatalog()
+
+/*
+	Creates a new, empty catalog
+*/
+func NewCatalog() *Catalog {
+	c := &Catalog{
+		universe:  internal.New(),
+		fallbacks: make(map[Locale][]Locale),
+		formats:   make(map[string]interface{}),
+	}
+	c.resolutions.Store(make(map[Locale]*resolution))
+	c.missHandler.Store(MissHandler(nil))
+
+	return c
+}
+
+/*
+	Releases the resources held by the catalog. The catalog must not be used afterwards.
+*/
+func (c *Catalog) Close() {
+	c.universe.Close()
+}
+
+/*
+	Adds a language provider to the catalog. See the package-level function Register
+	for details.
+*/
+func (c *Catalog) Register(p LanguageProvider) error {
+	return c.RegisterWithPriority(p, 0)
+}
+
+/*
+	Returned when registering a provider that Unregister could not identify: a value of a
+	type that cannot be compared, like a struct holding a map. Register a pointer instead.
+*/
+var ErrUnidentifiableProvider = errors.New("provider cannot be compared, register a pointer to it")
+
+/*
+	Adds a language provider to the catalog with an explicit priority. Where providers
+	define the same resource, the provider with the higher priority wins, regardless of the
+	order of registration. Among providers of equal priority, the one registered later wins.
+	Register uses priority 0. Fails with ErrUnidentifiableProvider, registering nothing,
+	if the provider could not be unregistered (See Unregister).
+*/
+func (c *Catalog) RegisterWithPriority(p LanguageProvider, priority int) error {
+	if !identifiable(p) {
+		return ErrUnidentifiableProvider
+	}
+
+	var lock sync.Mutex
+	codes := make(map[string][]string)
+	languages := make(chan types.Language)
+	errs := types.LoadErrors{}
+	name := providerName(p)
+
+	report := func(err error) {
+		err = annotate(err, name, "")
+		lock.Lock()
+		errs = append(errs, err)
+		lock.Unlock()
+
+		c.universe.Report(err)
+	}
+
+	enumerated := func() <-chan types.Language {
+		if e, ok := p.(ReportingEnumerator); ok {
+			return e.EnumerateReporting(report)
+		}
+		return p.Enumerate()
+	}()
+
+	go func() {
+		defer close(languages)
+		for l := range enumerated {
+			canonical := types.Canonicalize(l.Code)
+
+			lock.Lock()
+			known := codes[canonical]
+			codes[canonical] = append(known, l.Code)
+			lock.Unlock()
+
+			if known == nil {
+				l.Code = canonical
+				if l.Parent != "" {
+					l.Parent = types.Canonicalize(l.Parent)
+				}
+				languages <- l
+			}
+		}
+	}()
+
+	list := func(code string, report func(error)) <-chan types.LocatedResource {
+		if l, ok := p.(LocatingLister); ok {
+			return l.ListLocated(code, report)
+		}
+
+		var resources <-chan types.Resource
+		if l, ok := p.(ReportingLister); ok {
+			resources = l.ListReporting(code, report)
+		} else {
+			resources = p.List(code)
+		}
+
+		located := make(chan types.LocatedResource)
+		go func() {
+			defer close(located)
+			for resource := range resources {
+				located <- types.LocatedResource{resource, types.Origin{}, nil}
+			}
+		}()
+
+		return located
+	}
+
+	r := &registration{p}
+	c.registrationLock.Lock()
+	c.registrations = append(c.registrations, r)
+	c.registrationLock.Unlock()
+
+	// fetches the resources of every code of the provider the canonical code stands for
+	fetch := func(code string, report func(error), list func(string, func(error)) <-chan types.LocatedResource) <-chan types.LocatedResource {
+		lock.Lock()
+		sources := codes[code]
+		lock.Unlock()
+
+		annotated := func(err error) {
+			report(annotate(err, name, code))
+		}
+
+		resources := make(chan types.LocatedResource)
+		go func() {
+			defer close(resources)
+			for _, source := range sources {
+				for resource := range list(source, annotated) {
+					resource.Origin.Provider = name
+					resources <- resource
+				}
+			}
+		}()
+
+		return resources
+	}
+
+	if b, ok := p.(BundleLister); ok {
+		bundles := func(code string) []string {
+			lock.Lock()
+			sources := codes[code]
+			lock.Unlock()
+
+			seen := make(map[string]bool)
+			prefixes := []string{}
+			for _, source := range sources {
+				for _, prefix := range b.Bundles(source, func(err error) { report(annotate(err, name, code)) }) {
+					if !seen[prefix] {
+						seen[prefix] = true
+						prefixes = append(prefixes, prefix)
+					}
+				}
+			}
+
+			return prefixes
+		}
+
+		c.universe.RegisterBundles(r, priority, languages, bundles, func(code, prefix string, report func(error)) <-chan types.LocatedResource {
+			return fetch(code, report, func(source string, report func(error)) <-chan types.LocatedResource {
+				return b.ListBundle(source, prefix, report)
+			})
+		})
+	} else {
+		c.universe.RegisterPriority(r, priority, languages, func(code string, report func(error)) <-chan types.LocatedResource {
+			return fetch(code, report, list)
+		})
+	}
+
+	// languages of the provider may declare parents
+	c.forgetResolutions()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+/*
+	Removes a language provider from the catalog. Active locales it contributed to are rebuilt from
+	their remaining providers, while lookups keep seeing the previous resources until the rebuild is
+	complete. Locales without providers are removed. Unregistering a provider that was registered
+	more than once removes all of its registrations.
+
+	Providers are identified by ==, so pointers only match themselves, and values match equal
+	values. Maps, slices and functions are identified by their pointer. Values of types that
+	cannot be compared are refused by Register.
+*/
+func (c *Catalog) Unregister(p LanguageProvider) {
+	c.registrationLock.Lock()
+	removed := []*registration{}
+	remaining := []*registration{}
+	for _, r := range c.registrations {
+		if sameProvider(r.provider, p) {
+			removed = append(removed, r)
+		} else {
+			remaining = append(remaining, r)
+		}
+	}
+	c.registrations = remaining
+	c.registrationLock.Unlock()
+
+	for _, r := range removed {
+		c.universe.Unregister(r)
+	}
+	c.forgetResolutions()
+}
+
+/*
+	Fetches the resources of an active locale again from all of its providers, for example after
+	their files have changed. The resources are replaced atomically once all providers have
+	delivered; lookups keep seeing the previous resources until then. Returns the errors reported
+	while reloading. Inactive locales are loaded on activation anyway, and are left alone.
+
+	Reloading does not enumerate the languages of the providers again. To pick up a language a
+	provider did not offer when it was registered, unregister and register the provider. The
+	bundles of providers implementing BundleLister are listed again, though.
+*/
+func (c *Catalog) Reload(l Locale) error {
+	return c.universe.Reload(string(l.Canonical()))
+}
+
+// reports whether a provider can be compared by sameProvider. Comparing values panics if they hold
+// something that cannot be compared, like a map, even within a field of an interface type
+func identifiable(p LanguageProvider) (ok bool) {
+	switch reflect.TypeOf(p).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Func:
+		return true
+	}
+
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	return p == p
+}
+
+// compares providers by identity. Providers of types that cannot be compared, like maps, are
+// compared by their pointer
+func sameProvider(a, b LanguageProvider) bool {
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) {
+		return false
+	}
+
+	switch ta.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Func:
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+
+	return identifiable(b) && a == b
+}
+
+// describes a provider in error messages
+func providerName(p LanguageProvider) string {
+	if s, ok := p.(sysfmt.Stringer); ok {
+		return s.String()
+	}
+
+	return sysfmt.Sprintf("%T", p)
+}
+
+// turns an error into a *common.LoadError, and fills in the provider and code if missing
+func annotate(err error, provider, code string) error {
+	loadErr := &types.LoadError{Err: err}
+	if e, ok := err.(*types.LoadError); ok {
+		copied := *e
+		loadErr = &copied
+	}
+
+	if loadErr.Provider == "" {
+		loadErr.Provider = provider
+	}
+
+	if loadErr.Code == "" {
+		loadErr.Code = code
+	}
+
+	return loadErr
+}
+
+/*
+	Sets a function that is called for every error reported by a provider of the catalog, while
+	enumerating languages or loading resources. The function may be called from any goroutine.
+*/
+func (c *Catalog) SetErrorHandler(handler func(error)) {
+	c.universe.SetErrorHandler(handler)
+}
+
+/*
+	Enables or disables strict mode. In strict mode, activating a locale whose providers reported
+	errors while loading fails, and so do all lookups in this locale.
+*/
+func (c *Catalog) SetStrict(strict bool) {
+	c.universe.SetStrict(strict)
+}
+
+/*
+	Sets a function that is called for every resource found to be defined more than once in a
+	locale, when the resources of the locale are loaded or reloaded. Useful for logging accidental
+	shadowing:
+
+		c.SetConflictHandler(func(conflict common.Conflict) {
+			log.Print(conflict)
+		})
+
+	The function is called from a separate goroutine.
+*/
+func (c *Catalog) SetConflictHandler(handler func(types.Conflict)) {
+	c.universe.SetConflictHandler(handler)
+}
+
+/*
+	Calls the handler for every change of the resources of the catalog, until the returned function
+	is called. A change names the locale, the reason (loading, reloading, overrides, unregistering)
+	and the keys and bundle prefixes whose values lookups now see differently, so that caches can be
+	invalidated selectively:
+
+		stop := c.Watch(func(change common.Change) {
+			cache.Invalidate(ginta.Locale(change.Code), change.Prefixes...)
+		})
+		defer stop()
+
+	The handler is called from a separate goroutine, one change at a time and in the order the
+	changes were made. Changes that affect no key are not reported.
+*/
+func (c *Catalog) Watch(handler func(types.Change)) (stop func()) {
+	return c.universe.Watch(handler)
+}
+
+/*
+	Returns the resources of a locale that are defined more than once, either by different providers
+	or by different files of the same provider, together with the definition that wins (See
+	RegisterWithPriority) and the one it shadows. Only loaded resources are considered, so the locale
+	should be activated first.
+*/
+func (c *Catalog) Conflicts(l Locale) []types.Conflict {
+	return c.universe.Conflicts(string(l.Canonical()))
+}
+
+/*
+	Returns the errors reported while loading the resources of a locale
+*/
+func (c *Catalog) Errors(l Locale) []error {
+	return c.universe.Errors(string(l.Canonical()))
+}
+
+/*
+	Loads all resources of the locale and its fallback chain, including all bundles of providers
+	implementing BundleLister. See Locale.Activate
+*/
+func (c *Catalog) Activate(l Locale) error {
+	return c.ActivateContext(context.Background(), l)
+}
+
+/*
+	Like Activate, but gives up waiting once the context is done, returning the error of the context.
+	Giving up does not cancel the loading of the resources, which continues for other callers. See
+	Abandon and SetFetchTimeout for dealing with providers that never finish.
+*/
+func (c *Catalog) ActivateContext(ctx context.Context, l Locale) error {
+	_, _, err := c.activate(ctx, l, internal.Scope{"", true})
+	return err
+}
+
+/*
+	Abandons all fetches of a locale that are still running. Callers waiting for the locale resume,
+	and see the resources loaded so far by other providers. Resources delivered later by the abandoned
+	providers are discarded, and an error is recorded for each of them (See Errors).
+*/
+func (c *Catalog) Abandon(l Locale) {
+	c.universe.Abandon(string(l.Canonical()))
+}
+
+/*
+	Sets a timeout after which running fetches of a provider are abandoned automatically (See Abandon).
+	Applies to fetches started afterwards. Zero, the default, lets fetches run indefinitely.
+*/
+func (c *Catalog) SetFetchTimeout(timeout time.Duration) {
+	c.universe.SetFetchTimeout(timeout)
+}
+
+/*
+	Lists all languages of the catalog, by their canonical codes, with the properties their
+	providers describe them by. Where several providers offer a language, the first one
+	registered describes it, and later ones fill in the properties it left empty.
+*/
+func (c *Catalog) List() []*types.Language {
+	return c.universe.List()
+}
+
+/*
+	Returns the description of a locale, if a provider offers it. See List
+*/
+func (c *Catalog) Language(l Locale) (types.Language, bool) {
+	if lang, ok := c.universe.Language(string(l.Canonical())); ok {
+		return *lang, true
+	}
+
+	return types.Language{}, false
+}
+
+/*
+	Resolves a resource of the locale by its hierarchical key
+*/
+func (c *Catalog) ResolveResource(l Locale, k types.HierarchicalKey) (string, error) {
+	return c.ResolveResourceContext(context.Background(), l, k)
+}
+
+/*
+	Like ResolveResource, but fails with the error of the context if the locale is not
+	activated before the context is done
+*/
+func (c *Catalog) ResolveResourceContext(ctx context.Context, l Locale, k types.HierarchicalKey) (string, error) {
+	locale, chain, err := c.activate(ctx, l, keyScopes(string(k), true)...)
+	if err != nil {
+		return string(k), err
+	}
+
+	val, err := c.universe.Request(locale, string(k), true, chain...)
+	if err != nil {
+		return c.miss(Locale(locale), val, err)
+	}
+
+	return val, nil
+}
+
+/*
+	Resolves a resource like ResolveResource, but returns a trace of the resolution instead: every
+	key checked in the locale and its fallback chain, and where the matching resource is defined
+*/
+func (c *Catalog) Trace(l Locale, k types.HierarchicalKey) types.Trace {
+	locale, chain, _ := c.activate(context.Background(), l, keyScopes(string(k), true)...)
+	return c.universe.Trace(locale, string(k), true, chain...)
+}
+
+/*
+	Returns where the resource the locale returns for a key (See GetResource) is defined: the
+	provider, and the file and line for providers implementing LocatingLister. The origin is
+	zero for overrides. Returns false if the resource cannot be found.
+*/
+func (c *Catalog) Origin(l Locale, key string) (types.Origin, bool) {
+	locale, chain, _ := c.activate(context.Background(), l, keyScopes(key, false)...)
+	trace := c.universe.Trace(locale, key, false, chain...)
+	return trace.Origin, trace.Found
+}
+
+/*
+	Returns the metadata of the resource the locale returns for a key (See GetResource). Returns
+	false if the resource cannot be found, or its provider supplied no metadata.
+*/
+func (c *Catalog) Metadata(l Locale, key string) (types.Metadata, bool) {
+	locale, chain, _ := c.activate(context.Background(), l, keyScopes(key, false)...)
+	if trace := c.universe.Trace(locale, key, false, chain...); trace.Found && trace.Metadata != nil {
+		return *trace.Metadata, true
+	}
+
+	return types.Metadata{}, false
+}
+
+/*
+	Returns the resources of the locale itself below the prefix (all resources, for the empty
+	prefix), sorted by key, together with their origin and metadata. Resources of the fallback
+	chain are not included. Meant for exporting translations, and for linting them:
+
+		for _, res := range c.Export("de", "") {
+			if res.Metadata != nil && res.Metadata.Check(res.Value) != nil {
+				log.Printf("%s: %s is too long", res.Origin, res.Key)
+			}
+		}
+*/
+func (c *Catalog) Export(l Locale, prefix string) []types.LocatedResource {
+	locale, _, _ := c.activate(context.Background(), l, internal.Scope{prefix, true})
+	return c.universe.Resources(locale, prefix)
+}
+
+/*
+	Returns a resource of the locale by simple name matching. Failed lookups are passed to the
+	miss handler of the catalog (See SetMissHandler).
+*/
+func (c *Catalog) GetResource(l Locale, key string) (string, error) {
+	return c.GetResourceContext(context.Background(), l, key)
+}
+
+/*
+	Like GetResource, but fails with the error of the context if the locale is not
+	activated before the context is done
+*/
+func (c *Catalog) GetResourceContext(ctx context.Context, l Locale, key string) (string, error) {
+	locale, chain, err := c.activate(ctx, l, keyScopes(key, false)...)
+	if err != nil {
+		return key, err
+	}
+
+	val, err := c.universe.Request(locale, key, false, chain...)
+	if err != nil {
+		return c.miss(Locale(locale), val, err)
+	}
+
+	return val, nil
+}
+
+/*
+	Returns a resource bundle of the locale. See Locale.GetResourceBundle
+*/
+func (c *Catalog) GetResourceBundle(l Locale, prefix string) map[string]string {
+	locale, chain, _ := c.activate(context.Background(), l, bundleScopes(prefix, false)...)
+	return c.universe.RequestBundle(locale, prefix, false, chain...)
+}
+
+/*
+	Returns a resource bundle of the locale, combined with its parent bundles. See
+	Locale.ResolveResourceBundle
+*/
+func (c *Catalog) ResolveResourceBundle(l Locale, prefix string) map[string]string {
+	locale, chain, _ := c.activate(context.Background(), l, bundleScopes(prefix, true)...)
+	return c.universe.RequestBundle(locale, prefix, true, chain...)
+}
+
+/*
+	Registers a message format definition under a unique name. The catalog stores
+	definitions without interpreting them - the package fmt gives them their meaning.
+*/
+func (c *Catalog) RegisterFormat(name string, def interface{}) {
+	c.formatLock.Lock()
+	defer c.formatLock.Unlock()
+
+	c.formats[name] = def
+}
+
+/*
+	Returns the message format definition registered under the name
+*/
+func (c *Catalog) Format(name string) (interface{}, bool) {
+	c.formatLock.RLock()
+	defer c.formatLock.RUnlock()
+
+	def, ok := c.formats[name]
+	return def, ok
+}
@@ -0,0 +1,809 @@
+package ginta
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	types "github.com/beatgammit/ginta/common"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCatalogsIndependent(t *testing.T) {
+	c1, c2 := NewCatalog(), NewCatalog()
+	defer c1.Close()
+	defer c2.Close()
+
+	c1.Register(&mockProviderSingle{"c1", "key1", "val1"})
+	c2.Register(&mockProviderSingle{"c1", "key1", "other"})
+
+	if str, err := c1.GetResource("c1", "key1"); err != nil || str != "val1" {
+		t.Error(str, err)
+	}
+
+	if str, err := c2.GetResource("c1", "key1"); err != nil || str != "other" {
+		t.Error(str, err)
+	}
+
+	if str, err := Locale("c1").GetResource("key1"); err == nil {
+		t.Error(str, err)
+	}
+
+	if list := c1.List(); len(list) != 1 || list[0].Code != "c1" {
+		t.Error(list)
+	}
+}
+
+func TestCatalogFallbacks(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.SetFallbacks("es-MX", "es-419")
+
+	if chain := c.Fallbacks("es-MX"); len(chain) != 2 || chain[0] != "es-419" {
+		t.Error(chain)
+	}
+
+	if chain := Locale("es-MX").Fallbacks(); len(chain) != 2 || chain[0] != "es" {
+		t.Error(chain)
+	}
+}
+
+func TestCachedFallbacks(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(&mockProviderSingle{"es-419", "only", "latam"})
+	c.Register(&mockProviderSingle{"es", "only", "es"})
+
+	if str, err := c.GetResource("es_MX", "only"); err != nil || str != "es" {
+		t.Error(str, err)
+	}
+
+	// the cached chain is dropped when fallbacks change
+	c.SetFallbacks("es-MX", "es-419")
+	if str, err := c.GetResource("es_MX", "only"); err != nil || str != "latam" {
+		t.Error(str, err)
+	}
+
+	// and when providers declare parents
+	c.SetFallbacks("es-MX")
+	c.Register(mockProviderDescribed{Code: "es-MX", Parent: "es-419"})
+	if str, err := c.GetResource("es_MX", "only"); err != nil || str != "latam" {
+		t.Error(str, err)
+	}
+}
+
+func TestCatalogFormats(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.RegisterFormat("some", 42)
+
+	if def, ok := c.Format("some"); !ok || def != 42 {
+		t.Error(def, ok)
+	}
+
+	if def, ok := DefaultCatalog.Format("some"); ok {
+		t.Error(def)
+	}
+}
+
+type mockProviderFailing string
+
+func (m mockProviderFailing) Enumerate() <-chan types.Language {
+	return mockProviderEmpty(m).Enumerate()
+}
+
+func (m mockProviderFailing) List(code string) <-chan types.Resource {
+	return m.ListReporting(code, func(error) {})
+}
+
+func (m mockProviderFailing) ListReporting(_ string, report func(error)) <-chan types.Resource {
+	c := make(chan types.Resource)
+
+	go func() {
+		report(errors.New("broken"))
+		c <- types.Resource{"key1", "val1"}
+		close(c)
+	}()
+
+	return c
+}
+
+func TestCatalogLoadErrors(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	handled := make(chan error, 1)
+	c.SetErrorHandler(func(err error) {
+		handled <- err
+	})
+	c.Register(mockProviderFailing("fail"))
+
+	if err := c.Activate("fail"); err != nil {
+		t.Error(err)
+	}
+
+	loadErr, ok := (<-handled).(*types.LoadError)
+	if !ok || loadErr.Code != "fail" || loadErr.Provider != "ginta.mockProviderFailing" || loadErr.Err.Error() != "broken" {
+		t.Error(loadErr)
+	}
+
+	c.SetStrict(true)
+	if err := c.Activate("fail"); err == nil {
+		t.Error(c.Errors("fail"))
+	}
+
+	if str, err := c.GetResource("fail", "key1"); err == nil {
+		t.Error(str)
+	}
+}
+
+type mockProviderHanging chan bool
+
+func (m mockProviderHanging) Enumerate() <-chan types.Language {
+	return mockProviderEmpty("hang").Enumerate()
+}
+
+func (m mockProviderHanging) List(_ string) <-chan types.Resource {
+	c := make(chan types.Resource)
+
+	go func() {
+		c <- types.Resource{"key1", "val1"}
+		<-m
+		close(c)
+	}()
+
+	return c
+}
+
+func TestGetResourceContext(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	hang := make(mockProviderHanging)
+	defer close(hang)
+	c.Register(hang)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if str, err := c.GetResourceContext(ctx, "hang", "key1"); err != context.DeadlineExceeded {
+		t.Error(str, err)
+	}
+
+	c.Abandon("hang")
+
+	if err := c.ActivateContext(context.Background(), "hang"); err != nil {
+		t.Error(err)
+	}
+
+	if errs := c.Errors("hang"); len(errs) != 1 {
+		t.Error(errs)
+	}
+}
+
+type mockProviderValues map[string]string
+
+func (m mockProviderValues) Enumerate() <-chan types.Language {
+	return mockProviderEmpty("c5").Enumerate()
+}
+
+func (m mockProviderValues) List(_ string) <-chan types.Resource {
+	c := make(chan types.Resource, len(m))
+	for key, val := range m {
+		c <- types.Resource{key, val}
+	}
+	close(c)
+
+	return c
+}
+
+func TestReloadAndUnregister(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	single := &mockProviderSingle{"c5", "key1", "old"}
+	c.Register(single)
+	values := mockProviderValues{"key1": "overridden", "key2": "val2"}
+	c.Register(values)
+	c.Register(mockProviderEmpty("c6"))
+
+	if str, err := c.GetResource("c5", "key1"); err != nil || str != "overridden" {
+		t.Error(str, err)
+	}
+
+	c.Unregister(mockProviderValues{"key1": "overridden", "key2": "val2"})
+	if str, err := c.GetResource("c5", "key2"); err != nil || str != "val2" {
+		t.Error("unregistered a different map provider", str, err)
+	}
+
+	c.Unregister(values)
+	if str, err := c.GetResource("c5", "key2"); err == nil {
+		t.Error(str, err)
+	}
+
+	single.value = "new"
+	if str, err := c.GetResource("c5", "key1"); err != nil || str != "old" {
+		t.Error("resources changed before reloading", str, err)
+	}
+
+	if err := c.Reload("c5"); err != nil {
+		t.Error(err)
+	}
+
+	if str, err := c.GetResource("c5", "key1"); err != nil || str != "new" {
+		t.Error(str, err)
+	}
+
+	c.Unregister(mockProviderEmpty("c6"))
+	if list := c.List(); len(list) != 1 || list[0].Code != "c5" {
+		t.Error(list)
+	}
+}
+
+type mockProviderStruct struct {
+	values map[string]string
+}
+
+func (m mockProviderStruct) Enumerate() <-chan types.Language {
+	return mockProviderEmpty("c5a").Enumerate()
+}
+
+func (m mockProviderStruct) List(_ string) <-chan types.Resource {
+	return mockProviderValues(m.values).List("")
+}
+
+func TestRegisterUnidentifiable(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	if err := c.Register(mockProviderStruct{map[string]string{"key": "val"}}); err != ErrUnidentifiableProvider {
+		t.Error(err)
+	}
+	if list := c.List(); len(list) != 0 {
+		t.Error(list)
+	}
+
+	p := &mockProviderStruct{map[string]string{"key": "val"}}
+	if err := c.Register(p); err != nil {
+		t.Error(err)
+	}
+	c.Unregister(mockProviderStruct{map[string]string{"key": "val"}})
+	c.Unregister(p)
+	if list := c.List(); len(list) != 0 {
+		t.Error(list)
+	}
+}
+
+func TestRegisterWithPriority(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.RegisterWithPriority(&mockProviderSingle{"c7", "key1", "high"}, 10)
+	c.Register(&mockProviderSingle{"c7", "key1", "default"})
+	c.RegisterWithPriority(&mockProviderSingle{"c7", "key1", "low"}, -10)
+
+	if str, err := c.GetResource("c7", "key1"); err != nil || str != "high" {
+		t.Error(str, err)
+	}
+
+	if conflicts := c.Conflicts("c7"); len(conflicts) != 2 || conflicts[1].Key != "key1" || conflicts[1].Code != "c7" {
+		t.Error(conflicts)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	single := &mockProviderSingle{"c8", "key1", "old"}
+	c.Register(single)
+
+	c.SetOverride("c8", "key1", "overridden")
+	c.ApplyOverrides("c8", map[string]string{"key2": "added", "key3": "removed"})
+	c.DeleteOverride("c8", "key3")
+
+	single.value = "new"
+	if err := c.Reload("c8"); err != nil {
+		t.Error(err)
+	}
+
+	if str, err := c.GetResource("c8", "key1"); err != nil || str != "overridden" {
+		t.Error(str, err)
+	}
+
+	exported := c.Overrides("c8")
+	if len(exported) != 2 || exported["key2"] != "added" {
+		t.Error(exported)
+	}
+
+	c.ClearOverrides("c8")
+	if str, err := c.GetResource("c8", "key1"); err != nil || str != "new" {
+		t.Error(str, err)
+	}
+	if str, err := c.GetResource("c8", "key2"); err == nil {
+		t.Error(str)
+	}
+
+	c.ApplyOverrides("c8", exported)
+	if str, err := c.GetResource("c8", "key2"); err != nil || str != "added" {
+		t.Error(str, err)
+	}
+
+	// empty values are overrides like any other
+	c.ApplyOverrides("c8", map[string]string{"key1": ""}, "key2")
+	if str, err := c.GetResource("c8", "key1"); err != nil || str != "" {
+		t.Error(str, err)
+	}
+	if exported := c.Overrides("c8"); len(exported) != 1 || exported["key1"] != "" {
+		t.Error(exported)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	changes := make(chan types.Change, 1)
+	stop := c.Watch(func(change types.Change) {
+		changes <- change
+	})
+	defer stop()
+
+	c.Register(mockProviderEmpty("de"))
+	c.SetOverride("DE", "menu:file", "Datei")
+
+	select {
+	case change := <-changes:
+		if change.Code != "de" || change.Reason != types.ChangeOverridden || len(change.Keys) != 1 || change.Keys[0] != "menu:file" {
+			t.Error(change)
+		}
+	case <-time.After(time.Second):
+		t.Error("no change delivered")
+	}
+}
+
+type mockProviderTree string
+
+func (m mockProviderTree) Enumerate() <-chan types.Language {
+	return mockProviderEmpty(m).Enumerate()
+}
+
+func (m mockProviderTree) List(_ string) <-chan types.Resource {
+	return mockProviderValues{
+		"title":          "Title",
+		"menu:edit":      "Edit",
+		"menu:file":      "File",
+		"menu:file:open": "Open",
+		"menu:view:zoom": "Zoom",
+	}.List("")
+}
+
+func TestKeysAndSubtree(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderTree("c9"))
+
+	if keys := c.Keys("c9", "menu"); len(keys) != 4 || keys[0] != "menu:edit" || keys[3] != "menu:view:zoom" {
+		t.Error(keys)
+	}
+
+	if children := c.Children("c9", "menu"); len(children) != 2 || children[0] != "menu:file" || children[1] != "menu:view" {
+		t.Error(children)
+	}
+
+	if children := c.Children("c9", ""); len(children) != 1 || children[0] != "menu" {
+		t.Error(children)
+	}
+
+	tree := c.Subtree("c9", "menu")
+	file, ok := tree["file"].(Tree)
+	if len(tree) != 3 || tree["edit"] != "Edit" || !ok || file[""] != "File" || file["open"] != "Open" {
+		t.Error(tree)
+	}
+
+	if zoom := tree["view"].(Tree)["zoom"]; zoom != "Zoom" {
+		t.Error(zoom)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderTree("c10"))
+
+	trace := c.Trace("c10", "menu:view:edit")
+	match, ok := trace.Match()
+	if !ok || trace.Value != "Edit" || len(trace.Candidates) != 2 || match != (types.Candidate{"c10", "menu:edit"}) {
+		t.Error(trace)
+	}
+
+	if trace.Origin.Provider != "ginta.mockProviderTree" {
+		t.Error(trace.Origin)
+	}
+
+	str, err := c.ResolveResource("c10", "x:y")
+	var notFound *types.NotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, types.ErrResourceNotFound) || str != "x:y" {
+		t.Fatal(str, err)
+	}
+
+	expect := []types.Candidate{{"c10", "x:y"}, {"c10", "y"}, {"en", "x:y"}, {"en", "y"}}
+	if len(notFound.Candidates) != len(expect) || notFound.Code != "c10" {
+		t.Fatal(notFound)
+	}
+	for i, candidate := range expect {
+		if notFound.Candidates[i] != candidate {
+			t.Error(notFound.Candidates[i])
+		}
+	}
+
+	if !errors.Is(err, types.ResourceNotFoundError("x:y")) {
+		t.Error(err)
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(&mockProviderSingle{"c11", "key1", "val1"})
+	c.SetOverride("c11", "key2", "val2")
+
+	if origin, ok := c.Origin("c11", "key1"); !ok || origin.Provider != "*ginta.mockProviderSingle" || origin.String() != origin.Provider {
+		t.Error(origin, ok)
+	}
+
+	if origin, ok := c.Origin("c11", "key2"); !ok || origin != (types.Origin{}) {
+		t.Error(origin, ok)
+	}
+
+	if _, ok := c.Origin("c11", "key3"); ok {
+		t.Error("origin of a missing resource")
+	}
+}
+
+type mockProviderDescribed types.Language
+
+func (m mockProviderDescribed) Enumerate() <-chan types.Language {
+	c := make(chan types.Language, 1)
+	c <- types.Language(m)
+	close(c)
+
+	return c
+}
+
+func (m mockProviderDescribed) List(_ string) <-chan types.Resource {
+	return mockProviderValues{"key": m.Code}.List("")
+}
+
+func TestDeclaredParent(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderDescribed{Code: "pt-AO", Parent: "PT_pt"})
+	c.Register(mockProviderDescribed{Code: "pt-PT", DisplayName: "Portuguese (Portugal)"})
+	c.Register(mockProviderDescribed{Code: "pt-PT", DisplayName: "ignored", NativeName: "Português"})
+
+	if chain := c.Fallbacks("pt-AO"); len(chain) != 3 || chain[0] != "pt-PT" || chain[1] != "pt" {
+		t.Error(chain)
+	}
+
+	if lang, ok := c.Language("pt-pt"); !ok || lang.DisplayName != "Portuguese (Portugal)" || lang.NativeName != "Português" {
+		t.Error(lang, ok)
+	}
+
+	// the description of an unregistered provider goes with it
+	c.Register(mockProviderDescribed{Code: "pt-AO", DisplayName: "Portuguese (Angola)"})
+	c.Unregister(mockProviderDescribed{Code: "pt-AO", Parent: "PT_pt"})
+	if lang, ok := c.Language("pt-AO"); !ok || lang.Parent != "" || lang.DisplayName != "Portuguese (Angola)" {
+		t.Error(lang, ok)
+	}
+	if chain := c.Fallbacks("pt-AO"); len(chain) != 2 || chain[0] != "pt" {
+		t.Error(chain)
+	}
+}
+
+func TestDisplayNameOf(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderDescribed{Code: "de"})
+	c.ApplyOverrides("de", map[string]string{
+		"internal:languages:fr-CA": "Québécois",
+		"internal:regions:AT":      "Ösiland",
+	})
+
+	names := map[string]string{
+		"fr":      "Französisch",
+		"fr-CA":   "Québécois",
+		"de_AT":   "Deutsch (Ösiland)",
+		"zh-Hant": "Chinesisch (Traditionell)",
+		"pt-BR":   "Portugiesisch (Brasilien)",
+		"tlh":     "tlh",
+		"en-001":  "Englisch (001)",
+		"?":       "?",
+	}
+	for code, expected := range names {
+		if name := c.DisplayNameOf("de-CH", code); name != expected {
+			t.Error(code, name)
+		}
+	}
+
+	if name := c.DisplayNameOf("fr", "de-AT"); name != "allemand (Autriche)" {
+		t.Error(name)
+	}
+
+	if name := c.RegionNameOf("xx", "us"); name != "United States" {
+		t.Error(name)
+	}
+}
+
+func TestDisplayNameOfFallbackProvider(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderDescribed{Code: "de"})
+	c.Register(mockProviderDescribed{Code: "en"})
+	c.ApplyOverrides("en", map[string]string{
+		"internal:languages:fr":    "French!",
+		"internal:languages:pt-BR": "Brazilian",
+		"internal:languages:tlh":   "Klingon",
+	})
+
+	// names in German, built-in or not, beat names of the English provider
+	names := map[string]string{
+		"fr":    "Französisch",
+		"fr-CA": "Französisch (Kanada)",
+		"pt-BR": "Portugiesisch (Brasilien)",
+		"tlh":   "Klingon",
+	}
+	for code, expected := range names {
+		if name := c.DisplayNameOf("de", code); name != expected {
+			t.Error(code, name)
+		}
+	}
+
+	if name := c.DisplayNameOf("en", "pt-BR"); name != "Brazilian" {
+		t.Error(name)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderDescribed{Code: "en", DisplayName: "English"})
+	c.Register(mockProviderDescribed{Code: "de", DisplayName: "Deutsch"})
+	c.ApplyOverrides("en", map[string]string{"menu:file": "File", "menu:edit": "Edit", "menu:file:open": "Open", "title": "Ginta", "internal:NativeName": "English"})
+	c.ApplyOverrides("de", map[string]string{"menu:file": "Datei", "menu:edit": "Edit", "menu:help": "Hilfe"})
+
+	cov := c.Coverage("de", "")
+	if cov.Total != 5 || cov.Translated != 3 || cov.Ratio() != 0.6 {
+		t.Error(cov.Total, cov.Translated, cov.Ratio())
+	}
+	if fmt.Sprint(cov.Missing, cov.Extra, cov.Identical) != "[menu:file:open title] [menu:help] [menu:edit]" {
+		t.Error(cov.Missing, cov.Extra, cov.Identical)
+	}
+
+	if len(cov.Packages) != 2 || cov.Packages["menu"].Total != 3 || cov.Packages["menu:file"].Translated != 0 {
+		t.Error(cov.Packages)
+	}
+
+	cov = c.Coverage("de", "menu:file")
+	if cov.Total != 1 || cov.Ratio() != 0 || len(cov.Packages) != 0 {
+		t.Error(cov)
+	}
+
+	if cov = c.Coverage("en", "internal"); cov.Total != 1 || len(cov.Identical) != 1 {
+		t.Error(cov)
+	}
+}
+
+func TestMissHandler(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderDescribed{Code: "en"})
+	c.Register(mockProviderDescribed{Code: "de"})
+	c.ApplyOverrides("en", map[string]string{"title": "Title"})
+
+	c.GetResource("de", "key")
+	c.GetResource("de", "title")
+	c.GetResource("de", "missing")
+	c.ResolveResource("de", "missing:deeper")
+	if stats := c.Stats("DE"); stats != (types.Stats{2, 2, 1}) {
+		t.Error(stats)
+	}
+
+	// only registered languages are counted
+	c.GetResource("xx", "key")
+	var v expvar.Var = c.StatsVar()
+	if str := v.String(); str != `{"de":{"Hits":2,"Misses":2,"Fallbacks":1}}` {
+		t.Error(str)
+	}
+
+	var missed []string
+	c.SetMissHandler(func(l Locale, err *types.NotFoundError) (string, error) {
+		missed = append(missed, string(l)+" "+err.Key)
+		return "?", nil
+	})
+
+	if str, err := c.GetResource("de", "missing"); err != nil || str != "?" || fmt.Sprint(missed) != "[de missing]" {
+		t.Error(str, err, missed)
+	}
+	if str, err := c.GetResource("de", "title"); err != nil || str != "Title" || len(missed) != 1 {
+		t.Error(str, err, missed)
+	}
+
+	// names of languages are no misses
+	if c.DisplayNameOf("de", "tlh"); len(missed) != 1 {
+		t.Error(missed)
+	}
+
+	c.SetMissHandler(PanicOnMiss)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("no panic")
+		}
+	}()
+	c.GetResource("de", "missing")
+}
+
+func TestUsage(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderDescribed{Code: "en"})
+	c.ApplyOverrides("en", map[string]string{"menu": "Menu", "menu:file": "File", "title": "Title"})
+
+	c.GetResource("en", "title")
+	c.StartRecording()
+	c.GetResource("en", "menu:file")
+	c.ResolveResource("en-US", "dialog:title")
+	c.GetResource("en-US", "missing")
+	c.StopRecording()
+	c.GetResource("en", "title")
+
+	usage := c.Usage("en")
+	if fmt.Sprint(usage.Requested, usage.Resolved, usage.Missed, usage.Untouched) != "[menu:file] [menu:file] [] [key menu]" {
+		t.Error(usage)
+	}
+
+	usages := c.Usages()
+	if len(usages) != 2 || usages[1].Locale != "en-US" {
+		t.Fatal(usages)
+	}
+	if usage = usages[1]; fmt.Sprint(usage.Requested, usage.Resolved, usage.Missed, usage.Untouched) != "[dialog:title missing] [title] [missing] [key menu menu:file]" {
+		t.Error(usage)
+	}
+}
+
+func TestUsageFallback(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	c.Register(mockProviderDescribed{Code: "de"})
+	c.Register(mockProviderEmpty("de-AT"))
+	c.ApplyOverrides("de", map[string]string{"title": "Titel", "menu": "Menü"})
+
+	c.StartRecording()
+	c.GetResource("de-AT", "title")
+	c.StopRecording()
+
+	// de-AT has no resources of its own, so the title resolved is one of de
+	usage := c.Usage("de")
+	if fmt.Sprint(usage.Requested, usage.Resolved, usage.Untouched) != "[] [] [key menu]" {
+		t.Error(usage)
+	}
+
+	usage = c.Usage("de-AT")
+	if fmt.Sprint(usage.Requested, usage.Resolved, usage.Untouched) != "[title] [title] [key menu]" {
+		t.Error(usage)
+	}
+}
+
+// a provider of bundles that records the bundles listed
+type mockProviderBundles struct {
+	lock      sync.Mutex
+	resources map[string]string
+	listed    []string
+}
+
+func (m *mockProviderBundles) Enumerate() <-chan types.Language {
+	return mockProviderEmpty("c5").Enumerate()
+}
+
+func (m *mockProviderBundles) List(_ string) <-chan types.Resource {
+	return mockProviderValues(m.resources).List("")
+}
+
+func (m *mockProviderBundles) Bundles(_ string, _ func(error)) []string {
+	return []string{"", "menu", "menu:file", "dialog"}
+}
+
+func (m *mockProviderBundles) ListBundle(_, prefix string, _ func(error)) <-chan types.LocatedResource {
+	m.lock.Lock()
+	m.listed = append(m.listed, prefix)
+	m.lock.Unlock()
+
+	c := make(chan types.LocatedResource, len(m.resources))
+	for key, val := range m.resources {
+		if bundle, _ := types.HierarchicalKey(key).Split(); bundle == prefix || (prefix == "menu" && bundle != "menu:file" && strings.HasPrefix(bundle, "menu:")) {
+			c <- types.LocatedResource{types.Resource{key, val}, types.Origin{File: prefix}, nil}
+		}
+	}
+	close(c)
+
+	return c
+}
+
+func (m *mockProviderBundles) loaded() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	listed := append([]string{}, m.listed...)
+	sort.Strings(listed)
+	return fmt.Sprint(listed)
+}
+
+func TestBundles(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	p := &mockProviderBundles{resources: map[string]string{
+		"title":          "Title",
+		"menu:file":      "File",
+		"menu:file:open": "Open",
+		"menu:edit:cut":  "Cut",
+		"dialog:ok":      "OK",
+		"dialog:cancel":  "Cancel",
+	}}
+	c.Register(p)
+	c.RegisterWithPriority(mockProviderValues{"dialog:ok": "Okay", "menu:file:open": "Open..."}, -1)
+
+	if str, err := c.GetResource("c5", "menu:file"); err != nil || str != "File" || p.loaded() != "[ menu]" {
+		t.Error(str, err, p.loaded())
+	}
+
+	// the provider of lower priority loaded before does not win
+	if str, err := c.GetResource("c5", "menu:file:open"); err != nil || str != "Open" || p.loaded() != "[ menu menu:file]" {
+		t.Error(str, err, p.loaded())
+	}
+
+	if str, err := c.ResolveResource("c5", "menu:file:title"); err != nil || str != "Title" || p.loaded() != "[ menu menu:file]" {
+		t.Error(str, err, p.loaded())
+	}
+
+	if keys := c.Keys("c5", "dialog"); len(keys) != 2 || p.loaded() != "[ dialog menu menu:file]" {
+		t.Error(keys, p.loaded())
+	}
+
+	if err := c.Reload("c5"); err != nil || p.loaded() != "[  dialog dialog menu menu menu:file menu:file]" {
+		t.Error(err, p.loaded())
+	}
+
+	if str, err := c.GetResource("c5", "dialog:ok"); err != nil || str != "OK" {
+		t.Error(str, err)
+	}
+}
+
+func TestActivateLoadsAllBundles(t *testing.T) {
+	c := NewCatalog()
+	defer c.Close()
+
+	p := &mockProviderBundles{resources: map[string]string{"menu:edit:cut": "Cut"}}
+	c.Register(p)
+
+	if err := c.Activate("c5"); err != nil || p.loaded() != "[ dialog menu menu:file]" {
+		t.Error(err, p.loaded())
+	}
+}
@@ -0,0 +1,45 @@
+package common
+
+/*
+The reason the resources of a language changed
+*/
+type ChangeReason int
+
+const (
+	// Resources of a provider were loaded, on activation or after registration
+	ChangeLoaded ChangeReason = iota
+	// The language was reloaded
+	ChangeReloaded
+	// Overrides of the language were set or removed
+	ChangeOverridden
+	// A provider of the language was unregistered
+	ChangeUnregistered
+)
+
+func (r ChangeReason) String() string {
+	switch r {
+	case ChangeLoaded:
+		return "loaded"
+	case ChangeReloaded:
+		return "reloaded"
+	case ChangeOverridden:
+		return "overridden"
+	case ChangeUnregistered:
+		return "unregistered"
+	}
+
+	return "unknown"
+}
+
+/*
+Describes a change of the resources of a language, as seen by lookups
+*/
+type Change struct {
+	// Canonical code of the language
+	Code   string
+	Reason ChangeReason
+	// Full hierarchical keys of the resources that were added, changed or removed, sorted
+	Keys []string
+	// Prefixes of the bundles containing the keys, sorted
+	Prefixes []string
+}
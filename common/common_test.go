@@ -41,3 +41,30 @@ func TestHierarchyWalk(t *testing.T) {
 		key = key.Parent()
 	}
 }
+
+func TestLoadError(t *testing.T) {
+	cause := ResourceNotFoundError("x")
+	err := &LoadError{Provider: "fs:/tmp", Code: "de", File: "a.txt", Line: 3, Err: cause}
+
+	if err.Error() != "fs:/tmp [de]: a.txt:3: x" {
+		t.Error(err)
+	}
+
+	if err.Unwrap() != cause {
+		t.Error(err.Unwrap())
+	}
+
+	if str := (LoadErrors{err, err}).Error(); str != "fs:/tmp [de]: a.txt:3: x (and 1 more errors)" {
+		t.Error(str)
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	if str := (Origin{"fs:/tmp", "de/a.txt", 4}).String(); str != "fs:/tmp: de/a.txt:4" {
+		t.Error(str)
+	}
+
+	if str := (Origin{File: "de/a.txt"}).String(); str != "de/a.txt" {
+		t.Error(str)
+	}
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+A failure while enumerating languages or loading resources. Providers report
+the file and line (if applicable) and the underlying error. The catalog fills in
+the provider and language code before passing the error on.
+*/
+type LoadError struct {
+	// Description of the provider that failed
+	Provider string
+	// Canonical code of the language being loaded, empty during enumeration
+	Code string
+	// File (or other source) that failed, if known
+	File string
+	// Line within the file, if known (starting at 1)
+	Line int
+	// The underlying error
+	Err error
+}
+
+/*
+Formats the error as "provider [code]: file:line: error", leaving out unknown parts
+*/
+func (e *LoadError) Error() string {
+	parts := []string{}
+
+	if location := e.Provider; location != "" || e.Code != "" {
+		if e.Code != "" {
+			location += " [" + e.Code + "]"
+		}
+		parts = append(parts, strings.TrimSpace(location))
+	}
+
+	if e.File != "" {
+		file := e.File
+		if e.Line > 0 {
+			file += ":" + strconv.Itoa(e.Line)
+		}
+		parts = append(parts, file)
+	}
+
+	if e.Err != nil {
+		parts = append(parts, e.Err.Error())
+	}
+
+	return strings.Join(parts, ": ")
+}
+
+/*
+Returns the underlying error
+*/
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+/*
+A list of errors, reported together. Prints the first error, and the number of
+errors that follow it
+*/
+type LoadErrors []error
+
+func (e LoadErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no errors"
+	case 1:
+		return e[0].Error()
+	}
+
+	return e[0].Error() + " (and " + strconv.Itoa(len(e)-1) + " more errors)"
+}
+
+/*
+Returns the individual errors of the list
+*/
+func (e LoadErrors) Unwrap() []error {
+	return e
+}
@@ -0,0 +1,159 @@
+package common
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+/*
+The direction text of a language is written in
+*/
+type Direction int
+
+const (
+	// Not known
+	UnknownDirection Direction = iota
+	LeftToRight
+	RightToLeft
+)
+
+func (d Direction) String() string {
+	switch d {
+	case LeftToRight:
+		return "ltr"
+	case RightToLeft:
+		return "rtl"
+	}
+
+	return ""
+}
+
+/*
+A day of the week. The zero value means unknown
+*/
+type Weekday int
+
+const (
+	UnknownWeekday Weekday = iota
+	Monday
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+	Sunday
+)
+
+/*
+Converts the day to a time.Weekday. Fails for unknown days
+*/
+func (d Weekday) Weekday() (time.Weekday, bool) {
+	if d < Monday || d > Sunday {
+		return time.Sunday, false
+	}
+
+	return time.Weekday(d % 7), true
+}
+
+func (d Weekday) String() string {
+	if day, ok := d.Weekday(); ok {
+		return day.String()
+	}
+
+	return ""
+}
+
+/*
+Returned by Language.SetProperty for values it cannot interpret
+*/
+var ErrBadProperty = errors.New("malformed language property")
+
+// scripts written from right to left
+var rightToLeftScripts = map[string]bool{
+	"Adlm": true, "Arab": true, "Hebr": true, "Nkoo": true, "Rohg": true, "Syrc": true, "Thaa": true,
+}
+
+/*
+Sets a property of the language from a resource in a provider's bootstrap data:
+
+	internal:DisplayName     the display name
+	internal:NativeName      the native name
+	internal:Direction       ltr or rtl
+	internal:Script          the ISO 15924 script code
+	internal:Parent          the code of the parent language
+	internal:PluralRules     the name of the plural rule set
+	internal:FirstDayOfWeek  the English name of the day, e.g. monday
+
+Returns false for other keys, and fails with ErrBadProperty for values it cannot interpret.
+Setting the script also sets the direction, unless it is set explicitly.
+*/
+func (l *Language) SetProperty(key, value string) (bool, error) {
+	switch key {
+	case DisplayNameResourceKey:
+		l.DisplayName = value
+	case NativeNameResourceKey:
+		l.NativeName = value
+	case DirectionResourceKey:
+		switch strings.ToLower(value) {
+		case "ltr":
+			l.Direction = LeftToRight
+		case "rtl":
+			l.Direction = RightToLeft
+		default:
+			return true, ErrBadProperty
+		}
+	case ScriptResourceKey:
+		l.Script = value
+		if l.Direction == UnknownDirection {
+			l.Direction = LeftToRight
+			if rightToLeftScripts[value] {
+				l.Direction = RightToLeft
+			}
+		}
+	case ParentResourceKey:
+		l.Parent = value
+	case PluralRulesResourceKey:
+		l.PluralRules = value
+	case FirstDayOfWeekResourceKey:
+		for day := Monday; day <= Sunday; day++ {
+			if strings.EqualFold(day.String(), value) {
+				l.FirstDayOfWeek = day
+				return true, nil
+			}
+		}
+		return true, ErrBadProperty
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+/*
+Fills the fields of the language that are empty with those of another description
+of the same language
+*/
+func (l *Language) Merge(other Language) {
+	if l.DisplayName == "" {
+		l.DisplayName = other.DisplayName
+	}
+	if l.NativeName == "" {
+		l.NativeName = other.NativeName
+	}
+	if l.Direction == UnknownDirection {
+		l.Direction = other.Direction
+	}
+	if l.Script == "" {
+		l.Script = other.Script
+	}
+	if l.Parent == "" {
+		l.Parent = other.Parent
+	}
+	if l.PluralRules == "" {
+		l.PluralRules = other.PluralRules
+	}
+	if l.FirstDayOfWeek == UnknownWeekday {
+		l.FirstDayOfWeek = other.FirstDayOfWeek
+	}
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+/*
+Returned by Metadata.Check for values longer than the maximum length
+*/
+var ErrTooLong = errors.New("value exceeds the maximum length")
+
+/*
+Information about a resource for translators and tools, such as linters. Providers
+may attach it to the resources they list (See LocatedResource)
+*/
+type Metadata struct {
+	// Description of the resource, or the context it is used in
+	Description string
+	// Maximum length of the value in characters, or 0 if unlimited
+	MaxLength int
+	// The translation needs to be reviewed
+	Fuzzy bool
+	// The translation has been reviewed and approved
+	Approved bool
+}
+
+/*
+Checks a value against the constraints of the metadata. Fails with ErrTooLong
+for values with more characters than MaxLength
+*/
+func (m Metadata) Check(value string) error {
+	if m.MaxLength > 0 && utf8.RuneCountInString(value) > m.MaxLength {
+		return ErrTooLong
+	}
+
+	return nil
+}
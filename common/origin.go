@@ -0,0 +1,69 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+The place a resource is defined at. Providers fill in the file and line, if they know
+them (See ginta.LocatingLister). The catalog fills in the provider.
+*/
+type Origin struct {
+	// Description of the provider that defines the resource
+	Provider string
+	// File (or other source) the resource is defined in, if known
+	File string
+	// Line within the file, if known (starting at 1)
+	Line int
+}
+
+/*
+Formats the origin as "provider: file:line", leaving out unknown parts
+*/
+func (o Origin) String() string {
+	parts := []string{}
+
+	if o.Provider != "" {
+		parts = append(parts, o.Provider)
+	}
+
+	if o.File != "" {
+		file := o.File
+		if o.Line > 0 {
+			file += ":" + strconv.Itoa(o.Line)
+		}
+		parts = append(parts, file)
+	}
+
+	return strings.Join(parts, ": ")
+}
+
+/*
+A resource, together with the place it is defined at, and its metadata (nil if none)
+*/
+type LocatedResource struct {
+	Resource
+	Origin   Origin
+	Metadata *Metadata
+}
+
+/*
+A resource defined more than once for the same language. The definition of the winner
+is used, the one of the loser is shadowed
+*/
+type Conflict struct {
+	// Canonical code of the language
+	Code string
+	// Full hierarchical key of the resource
+	Key    string
+	Winner Origin
+	Loser  Origin
+}
+
+/*
+Describes the conflict in a single line, suitable for logging
+*/
+func (c Conflict) String() string {
+	return c.Code + ": " + strconv.Quote(c.Key) + " from " + c.Winner.String() + " shadows " + c.Loser.String()
+}
@@ -0,0 +1,14 @@
+package common
+
+/*
+Counts the lookups of single resources in a language (See ginta.Locale.GetResource and
+ginta.Locale.ResolveResource)
+*/
+type Stats struct {
+	// Lookups that found a resource, in the language or its fallback chain
+	Hits uint64
+	// Lookups that found no resource
+	Misses uint64
+	// Hits served by a language of the fallback chain, rather than the language itself
+	Fallbacks uint64
+}
@@ -0,0 +1,298 @@
+package common
+
+import (
+	"sort"
+	"strings"
+)
+
+/*
+A parsed BCP 47 language tag (RFC 5646), such as "de-AT", "zh-Hant-TW" or
+"de-DE-u-co-phonebk". All fields are stored in their canonical case. Extensions
+(except private use) are sorted by their singleton, and the keywords of a Unicode
+(-u-) extension are sorted by key.
+*/
+type Tag struct {
+	// primary language subtag, lower case (en)
+	Language string
+	// script subtag, title case (Latn)
+	Script string
+	// region subtag, upper case or three digits (US, 419)
+	Region string
+	// variant subtags, lower case (1996)
+	Variants []string
+	// extensions, each starting with its singleton (u-ca-gregory)
+	Extensions []string
+	// private use subtags, including the leading x (x-private)
+	PrivateUse string
+}
+
+/*
+Error returned for language codes that are not well-formed BCP 47 tags. The
+error prints the offending code.
+*/
+type InvalidTagError string
+
+func (e InvalidTagError) Error() string {
+	return "invalid language tag: " + string(e)
+}
+
+const (
+	// Separator between the subtags of a language tag
+	TagSeparator = "-"
+	// Private use singleton
+	privateUseSingleton = "x"
+	// Unicode locale extension singleton
+	unicodeSingleton = "u"
+)
+
+// deprecated language subtags, and three-letter codes with a two-letter equivalent
+var languageAliases = map[string]string{
+	"iw":  "he",
+	"in":  "id",
+	"ji":  "yi",
+	"jw":  "jv",
+	"mo":  "ro",
+	"ara": "ar",
+	"deu": "de",
+	"ger": "de",
+	"eng": "en",
+	"fra": "fr",
+	"fre": "fr",
+	"ita": "it",
+	"jpn": "ja",
+	"kor": "ko",
+	"nld": "nl",
+	"dut": "nl",
+	"por": "pt",
+	"rus": "ru",
+	"spa": "es",
+	"zho": "zh",
+	"chi": "zh",
+}
+
+/*
+Parses a language code into its tag. Both "-" and "_" are accepted as subtag separators, and the
+case of the input is irrelevant. Deprecated language codes are replaced by their modern equivalent,
+and extended language subtags (zh-yue) by their primary language (yue). Returns an InvalidTagError
+if the code is not well-formed.
+*/
+func ParseTag(code string) (Tag, error) {
+	var tag Tag
+	subtags := strings.Split(strings.ToLower(strings.Replace(code, "_", TagSeparator, -1)), TagSeparator)
+	i := 0
+
+	next := func(valid func(string) bool) (string, bool) {
+		if i < len(subtags) && valid(subtags[i]) {
+			i++
+			return subtags[i-1], true
+		}
+		return "", false
+	}
+
+	lang, ok := next(isLanguage)
+	if !ok {
+		return tag, InvalidTagError(code)
+	}
+
+	if len(lang) <= 3 {
+		if extlang, ok := next(isExtlang); ok {
+			lang = extlang
+		}
+	}
+
+	if alias, ok := languageAliases[lang]; ok {
+		lang = alias
+	}
+	tag.Language = lang
+
+	if script, ok := next(isScript); ok {
+		tag.Script = strings.ToUpper(script[:1]) + script[1:]
+	}
+
+	if region, ok := next(isRegion); ok {
+		tag.Region = strings.ToUpper(region)
+	}
+
+	for variant, ok := next(isVariant); ok; variant, ok = next(isVariant) {
+		for _, known := range tag.Variants {
+			if known == variant {
+				return Tag{}, InvalidTagError(code)
+			}
+		}
+		tag.Variants = append(tag.Variants, variant)
+	}
+
+	singletons := make(map[string]bool)
+	for i < len(subtags) && subtags[i] != privateUseSingleton {
+		singleton := subtags[i]
+		if len(singleton) != 1 || !isAlphanumeric(singleton) || singletons[singleton] {
+			return Tag{}, InvalidTagError(code)
+		}
+		singletons[singleton] = true
+		i++
+
+		start := i
+		for i < len(subtags) && len(subtags[i]) >= 2 && len(subtags[i]) <= 8 && isAlphanumeric(subtags[i]) {
+			i++
+		}
+
+		if start == i {
+			return Tag{}, InvalidTagError(code)
+		}
+
+		ext := subtags[start:i]
+		if singleton == unicodeSingleton {
+			ext = sortKeywords(ext)
+		}
+
+		tag.Extensions = append(tag.Extensions, singleton+TagSeparator+strings.Join(ext, TagSeparator))
+	}
+	sort.Strings(tag.Extensions)
+
+	if i < len(subtags) {
+		start := i
+		for i++; i < len(subtags) && len(subtags[i]) >= 1 && len(subtags[i]) <= 8 && isAlphanumeric(subtags[i]); i++ {
+		}
+
+		if i != len(subtags) || i == start+1 {
+			return Tag{}, InvalidTagError(code)
+		}
+
+		tag.PrivateUse = strings.Join(subtags[start:], TagSeparator)
+	}
+
+	return tag, nil
+}
+
+/*
+Returns the canonical form of a language code. Codes that are not well-formed are returned
+unchanged, so that providers using arbitrary identifiers for their languages keep working.
+*/
+func Canonicalize(code string) string {
+	if tag, err := ParseTag(code); err == nil {
+		return tag.String()
+	}
+
+	return code
+}
+
+/*
+Formats the tag in its canonical form
+*/
+func (t Tag) String() string {
+	parts := []string{t.Language}
+
+	for _, part := range []string{t.Script, t.Region} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	parts = append(parts, t.Variants...)
+	parts = append(parts, t.Extensions...)
+
+	if t.PrivateUse != "" {
+		parts = append(parts, t.PrivateUse)
+	}
+
+	return strings.Join(parts, TagSeparator)
+}
+
+/*
+Returns the next more general tag. Extensions and private use subtags are removed first, then
+the variants one at a time, then the region and finally the script. The parent of a plain
+language tag is the zero tag.
+
+Examples:
+	Parent(de-DE-u-co-phonebk) = de-DE
+	Parent(zh-Hant-TW) = zh-Hant
+	Parent(zh-Hant) = zh
+	Parent(zh) = ""
+*/
+func (t Tag) Parent() Tag {
+	switch {
+	case len(t.Extensions) > 0 || t.PrivateUse != "":
+		t.Extensions = nil
+		t.PrivateUse = ""
+	case len(t.Variants) > 0:
+		t.Variants = t.Variants[:len(t.Variants)-1]
+		if len(t.Variants) == 0 {
+			t.Variants = nil
+		}
+	case t.Region != "":
+		t.Region = ""
+	case t.Script != "":
+		t.Script = ""
+	default:
+		return Tag{}
+	}
+
+	return t
+}
+
+/*
+Returns true for the zero tag, which has no language
+*/
+func (t Tag) IsZero() bool {
+	return t.Language == ""
+}
+
+// sorts the keywords of a unicode extension by key, keeping its attributes in front
+func sortKeywords(subtags []string) []string {
+	attributes := []string{}
+	keywords := [][]string{}
+
+	for _, subtag := range subtags {
+		switch {
+		case len(subtag) == 2:
+			keywords = append(keywords, []string{subtag})
+		case len(keywords) > 0:
+			keywords[len(keywords)-1] = append(keywords[len(keywords)-1], subtag)
+		default:
+			attributes = append(attributes, subtag)
+		}
+	}
+
+	sort.SliceStable(keywords, func(i, j int) bool {
+		return keywords[i][0] < keywords[j][0]
+	})
+
+	result := attributes
+	for _, keyword := range keywords {
+		result = append(result, keyword...)
+	}
+
+	return result
+}
+
+func isLanguage(s string) bool {
+	return (len(s) >= 2 && len(s) <= 3 || len(s) >= 5 && len(s) <= 8) && isAlpha(s)
+}
+
+func isExtlang(s string) bool {
+	return len(s) == 3 && isAlpha(s)
+}
+
+func isScript(s string) bool {
+	return len(s) == 4 && isAlpha(s)
+}
+
+func isRegion(s string) bool {
+	return len(s) == 2 && isAlpha(s) || len(s) == 3 && isDigit(s)
+}
+
+func isVariant(s string) bool {
+	return (len(s) >= 5 && len(s) <= 8 || len(s) == 4 && isDigit(s[:1])) && isAlphanumeric(s)
+}
+
+func isAlpha(s string) bool {
+	return strings.Trim(s, "abcdefghijklmnopqrstuvwxyz") == ""
+}
+
+func isDigit(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
+
+func isAlphanumeric(s string) bool {
+	return strings.Trim(s, "abcdefghijklmnopqrstuvwxyz0123456789") == ""
+}
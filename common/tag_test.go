@@ -0,0 +1,68 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	expect := map[string]string{
+		"en":                            "en",
+		"EN-us":                         "en-US",
+		"en_US":                         "en-US",
+		"zh-hant-tw":                    "zh-Hant-TW",
+		"es-419":                        "es-419",
+		"de-ch-1996":                    "de-CH-1996",
+		"iw":                            "he",
+		"deu-AT":                        "de-AT",
+		"zh-yue-HK":                     "yue-HK",
+		"de-DE-U-KN-true-CO-phonebk":    "de-DE-u-co-phonebk-kn-true",
+		"en-x-private-u-ignored":        "en-x-private-u-ignored",
+		"sl-rozaj-biske-t-en-u-nu-latn": "sl-rozaj-biske-t-en-u-nu-latn",
+		"l1":                            "l1",
+		"not a tag":                     "not a tag",
+	}
+
+	for in, out := range expect {
+		if result := Canonicalize(in); result != out {
+			t.Errorf("%s: expected %s but got %s", in, out, result)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	tag, err := ParseTag("sr_latn_rs_u_ca_gregory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tag.Language != "sr" || tag.Script != "Latn" || tag.Region != "RS" || len(tag.Extensions) != 1 || tag.Extensions[0] != "u-ca-gregory" {
+		t.Error(tag)
+	}
+}
+
+func TestInvalidTags(t *testing.T) {
+	for _, code := range []string{"", "e", "abcd", "en--US", "en-US-u", "en-u-ca-u-nu", "de-1996-1996", "en-x", "en-x-waytoolong"} {
+		if tag, err := ParseTag(code); err == nil {
+			t.Error(code, tag)
+		} else if _, ok := err.(InvalidTagError); !ok {
+			t.Error(err)
+		}
+	}
+}
+
+func TestTagParent(t *testing.T) {
+	tag, _ := ParseTag("zh-Hant-TW-u-co-pinyin")
+	expect := []string{"zh-Hant-TW-u-co-pinyin", "zh-Hant-TW", "zh-Hant", "zh"}
+
+	for _, expectStr := range expect {
+		if tag.String() != expectStr {
+			t.Error(tag)
+		}
+
+		tag = tag.Parent()
+	}
+
+	if !tag.IsZero() {
+		t.Error(tag)
+	}
+}
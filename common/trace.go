@@ -0,0 +1,89 @@
+package common
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+Matched by every lookup failure, using errors.Is
+*/
+var ErrResourceNotFound = errors.New("resource not found")
+
+/*
+A key looked up in a single language, while resolving a resource
+*/
+type Candidate struct {
+	// Canonical code of the language
+	Code string
+	// Full hierarchical key
+	Key string
+}
+
+func (c Candidate) String() string {
+	return c.Code + ":" + c.Key
+}
+
+/*
+Records how a resource was looked up: every candidate key checked, in order, for the
+requested language and each language of its fallback chain. If the resource was found,
+the last candidate is the one that matched.
+*/
+type Trace struct {
+	// The key requested
+	Key string
+	// Canonical code of the language requested
+	Code       string
+	Candidates []Candidate
+	Found      bool
+	Value      string
+	// Where the matching resource is defined. Zero for overrides, and if not found
+	Origin Origin
+	// Metadata of the matching resource, if any
+	Metadata *Metadata
+}
+
+/*
+Returns the candidate that matched, if the resource was found
+*/
+func (t Trace) Match() (Candidate, bool) {
+	if !t.Found || len(t.Candidates) == 0 {
+		return Candidate{}, false
+	}
+
+	return t.Candidates[len(t.Candidates)-1], true
+}
+
+/*
+The error of a failed lookup, listing the candidates checked (See Trace). Matches
+ErrResourceNotFound and ResourceNotFoundError of the same key with errors.Is, and
+unwraps to the latter.
+*/
+type NotFoundError struct {
+	// The key requested
+	Key string
+	// Canonical code of the language requested
+	Code       string
+	Candidates []Candidate
+}
+
+func (e *NotFoundError) Error() string {
+	tried := make([]string, len(e.Candidates))
+	for i, c := range e.Candidates {
+		tried[i] = c.String()
+	}
+
+	return "resource " + strconv.Quote(e.Key) + " not found for " + e.Code + " (tried " + strings.Join(tried, ", ") + ")"
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrResourceNotFound
+}
+
+/*
+Returns the key as a ResourceNotFoundError
+*/
+func (e *NotFoundError) Unwrap() error {
+	return ResourceNotFoundError(e.Key)
+}
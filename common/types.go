@@ -13,9 +13,25 @@ and a human-readable name.  The requirement for a human-readable name
 implies that usually, there needs to be some kind of bootstrapping for any 
 language provider (to map the language code to that name). Each provider
 is responsible for its own method.
+
+The remaining fields are optional, and left empty if the provider does not
+know them. Providers reading resources may take them from the resources under
+the internal: keys (See SetProperty).
 */
 type Language struct {
 	Code, DisplayName string
+	// Name of the language in the language itself, e.g. "Deutsch"
+	NativeName string
+	// Direction of text. Derived from the script if not set explicitly
+	Direction Direction
+	// ISO 15924 code of the script, e.g. "Latn"
+	Script string
+	// Code of the language to fall back to, if it differs from the one derived from the code
+	Parent string
+	// Name of the plural rule set, e.g. "one_other"
+	PluralRules string
+	// First day of the week, if known
+	FirstDayOfWeek Weekday
 }
 
 /*
@@ -41,6 +57,22 @@ const (
 		part of some bootstrapping process for a language 
 	*/
 	DisplayNameResourceKey = "internal:DisplayName"
+	/*
+		Resource keys for the optional properties of a language (See Language.SetProperty)
+	*/
+	NativeNameResourceKey     = "internal:NativeName"
+	DirectionResourceKey      = "internal:Direction"
+	ScriptResourceKey         = "internal:Script"
+	ParentResourceKey         = "internal:Parent"
+	PluralRulesResourceKey    = "internal:PluralRules"
+	FirstDayOfWeekResourceKey = "internal:FirstDayOfWeek"
+	/*
+		Prefixes of the bundles holding the names of languages (by code, e.g. internal:languages:fr),
+		regions (e.g. internal:regions:FR) and scripts (e.g. internal:scripts:Latn) in a language
+	*/
+	LanguageNamesPrefix = "internal:languages"
+	RegionNamesPrefix   = "internal:regions"
+	ScriptNamesPrefix   = "internal:scripts"
 )
 
 /*
@@ -0,0 +1,37 @@
+package ginta
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const localeKey contextKey = 0
+
+/*
+	Returns a copy of the context that carries the locale
+*/
+func NewContext(ctx context.Context, l Locale) context.Context {
+	return context.WithValue(ctx, localeKey, l)
+}
+
+/*
+	Returns the locale stored in the context, if any
+*/
+func FromContext(ctx context.Context) (Locale, bool) {
+	l, ok := ctx.Value(localeKey).(Locale)
+	return l, ok
+}
+
+/*
+	Returns the locale stored in the context, or the DefaultLocale if there is none. The
+	result can be passed directly to functions expecting a locale, such as fmt.NewResolver
+	or fmt.ApplyFormat.
+*/
+func LocaleFromContext(ctx context.Context) Locale {
+	if l, ok := FromContext(ctx); ok {
+		return l
+	}
+
+	return DefaultLocale
+}
@@ -0,0 +1,125 @@
+package ginta
+
+import (
+	types "github.com/beatgammit/ginta/common"
+	"sort"
+	"strings"
+)
+
+/*
+	Compares the resources of a locale below a prefix with those of the reference language
+	(DefaultLocale). Only resources the locale defines itself count: resources it inherits
+	from its fallback chain are missing. Resources below "internal" (the description of the
+	language, see common.Language) are left out, unless the prefix is within "internal".
+*/
+type Coverage struct {
+	// The package compared, or the empty string for all resources
+	Prefix string
+	// The number of keys of the reference language
+	Total int
+	// The number of keys of the reference language the locale defines
+	Translated int
+	// The keys of the reference language the locale does not define, sorted
+	Missing []string
+	// The keys the locale defines, but the reference language does not, sorted
+	Extra []string
+	// The keys the locale defines with the value of the reference language, sorted. These
+	// are likely untranslated
+	Identical []string
+	// The coverage of every package below the prefix (at any depth) by its full prefix, if
+	// returned by Catalog.Coverage. Packages of the statistics have no packages themselves
+	Packages map[string]*Coverage
+}
+
+/*
+	Returns the share of the keys of the reference language the locale defines, between 0 and 1.
+	Packages without any keys in the reference language are fully covered. Example, for a CI gate:
+
+		if cov := Locale("de").Coverage("menu"); cov.Ratio() < 0.95 || len(cov.Identical) > 0 {
+			log.Fatalf("menu is translated to %.0f%%, missing: %v", cov.Ratio()*100, cov.Missing)
+		}
+*/
+func (c *Coverage) Ratio() float64 {
+	if c.Total == 0 {
+		return 1
+	}
+
+	return float64(c.Translated) / float64(c.Total)
+}
+
+/*
+	Returns the coverage of this locale below the prefix (all resources, for the empty prefix)
+	with respect to the DefaultLocale. Applies to the DefaultCatalog.
+*/
+func (l Locale) Coverage(prefix string) *Coverage {
+	return DefaultCatalog.Coverage(l, prefix)
+}
+
+/*
+	Returns the coverage of a locale below the prefix. See Locale.Coverage
+*/
+func (c *Catalog) Coverage(l Locale, prefix string) *Coverage {
+	reference := make(map[string]string)
+	for _, res := range c.Export(DefaultLocale, prefix) {
+		if covered(res.Key, prefix) {
+			reference[res.Key] = res.Value
+		}
+	}
+
+	result := &Coverage{prefix, 0, 0, []string{}, []string{}, []string{}, make(map[string]*Coverage)}
+	count := func(key string, add func(*Coverage)) {
+		add(result)
+		segments := relative(key, prefix)
+		for i := 1; i < len(segments); i++ {
+			pkg := strings.Join(segments[:i], types.ResourceKeySegmentSeparator)
+			if prefix != "" {
+				pkg = prefix + types.ResourceKeySegmentSeparator + pkg
+			}
+
+			cov, ok := result.Packages[pkg]
+			if !ok {
+				cov = &Coverage{pkg, 0, 0, []string{}, []string{}, []string{}, nil}
+				result.Packages[pkg] = cov
+			}
+			add(cov)
+		}
+	}
+
+	defined := make(map[string]bool)
+	for _, res := range c.Export(l, prefix) {
+		if !covered(res.Key, prefix) {
+			continue
+		}
+
+		key, val := res.Key, res.Value
+		defined[key] = true
+		if original, ok := reference[key]; !ok {
+			count(key, func(cov *Coverage) { cov.Extra = append(cov.Extra, key) })
+		} else {
+			count(key, func(cov *Coverage) { cov.Total++; cov.Translated++ })
+			if val == original {
+				count(key, func(cov *Coverage) { cov.Identical = append(cov.Identical, key) })
+			}
+		}
+	}
+
+	for key := range reference {
+		if !defined[key] {
+			count(key, func(cov *Coverage) { cov.Total++; cov.Missing = append(cov.Missing, key) })
+		}
+	}
+
+	// resources are exported sorted, but missing ones are collected from a map
+	sort.Strings(result.Missing)
+	for _, cov := range result.Packages {
+		sort.Strings(cov.Missing)
+	}
+
+	return result
+}
+
+// reports whether a key below the prefix takes part in coverage
+func covered(key, prefix string) bool {
+	internal := "internal" + types.ResourceKeySegmentSeparator
+	return !strings.HasPrefix(key, internal) || strings.HasPrefix(prefix+types.ResourceKeySegmentSeparator, internal)
+}
@@ -0,0 +1,204 @@
+package ginta
+
+import (
+	"context"
+	types "github.com/beatgammit/ginta/common"
+	"github.com/beatgammit/ginta/internal"
+)
+
+/*
+	Overrides the fallback chain of a locale. When a resource cannot be found in the locale
+	itself, the locales of the chain are tried in the given order. The DefaultLocale always
+	terminates a chain, and needs not be specified explicitly. Calling SetFallbacks without
+	a chain restores the derived chain of the locale. Applies to the DefaultCatalog.
+
+	Example:
+		SetFallbacks("es-MX", "es-419", "es")
+*/
+func SetFallbacks(l Locale, chain ...Locale) {
+	DefaultCatalog.SetFallbacks(l, chain...)
+}
+
+/*
+	Overrides the fallback chain of a locale within the catalog. See SetFallbacks
+*/
+func (c *Catalog) SetFallbacks(l Locale, chain ...Locale) {
+	c.fallbackLock.Lock()
+	defer c.fallbackLock.Unlock()
+
+	if len(chain) == 0 {
+		delete(c.fallbacks, l.Canonical())
+	} else {
+		c.fallbacks[l.Canonical()] = canonical(chain)
+	}
+	c.forgetResolutions()
+}
+
+/*
+	Returns the fallback chain of this locale, not including the locale itself. Unless
+	overridden by SetFallbacks, the chain is derived from the locale code by successively
+	removing its last subtag (de-CH-1996 -> de-CH -> de, see common.Tag.Parent), and terminated
+	by the DefaultLocale. Languages whose provider declares a parent (See common.Language)
+	continue the chain with that parent instead. All locales of the chain are canonical.
+*/
+func (l Locale) Fallbacks() []Locale {
+	return DefaultCatalog.Fallbacks(l)
+}
+
+/*
+	Returns the fallback chain of a locale within the catalog. See Locale.Fallbacks
+*/
+func (c *Catalog) Fallbacks(l Locale) []Locale {
+	l = l.Canonical()
+
+	c.fallbackLock.RLock()
+	chain, ok := c.fallbacks[l]
+	c.fallbackLock.RUnlock()
+
+	if !ok {
+		chain = []Locale{}
+		derived := map[Locale]bool{l: true}
+		for next := c.parent(l); next != "" && !derived[next]; next = c.parent(next) {
+			derived[next] = true
+			chain = append(chain, next)
+		}
+	}
+
+	result := make([]Locale, 0, len(chain)+1)
+	seen := map[Locale]bool{l: true}
+	for _, next := range append(chain, DefaultLocale.Canonical()) {
+		if !seen[next] {
+			seen[next] = true
+			result = append(result, next)
+		}
+	}
+
+	return result
+}
+
+// returns the parent a provider declared for the locale (See common.Language), or the one
+// derived from its code. Returns the empty locale for locales without a parent
+func (c *Catalog) parent(l Locale) Locale {
+	if lang, ok := c.universe.Language(string(l)); ok && lang.Parent != "" {
+		return Locale(lang.Parent)
+	}
+
+	if tag, err := l.Tag(); err == nil {
+		return Locale(tag.Parent().String())
+	}
+
+	return ""
+}
+
+func canonical(locales []Locale) []Locale {
+	result := make([]Locale, len(locales))
+	for i, l := range locales {
+		result[i] = l.Canonical()
+	}
+
+	return result
+}
+
+// The canonical code of a locale and the codes of its fallback chain, derived with a DefaultLocale
+type resolution struct {
+	locale   string
+	codes    []string
+	fallback Locale
+}
+
+// the number of locales whose resolution is cached. The cache starts over once it is full, so that
+// lookups of arbitrary locales cannot grow it
+const maxResolutions = 1024
+
+// returns the canonical code of a locale and the codes of its fallback chain, which must not be
+// modified. Resolutions are cached by the locale as given, so that lookups neither parse the locale
+// nor derive its chain again
+func (c *Catalog) resolve(l Locale) *resolution {
+	if r, ok := c.resolutions.Load().(map[Locale]*resolution)[l]; ok && r.fallback == DefaultLocale {
+		return r
+	}
+
+	c.resolutionLock.Lock()
+	epoch := c.resolutionEpoch
+	c.resolutionLock.Unlock()
+
+	chain := c.Fallbacks(l)
+	r := &resolution{string(l.Canonical()), make([]string, len(chain)), DefaultLocale}
+	for i, next := range chain {
+		r.codes[i] = string(next)
+	}
+
+	c.resolutionLock.Lock()
+	defer c.resolutionLock.Unlock()
+
+	// fallbacks or providers changed while deriving the chain
+	if epoch != c.resolutionEpoch {
+		return r
+	}
+
+	current := c.resolutions.Load().(map[Locale]*resolution)
+	next := make(map[Locale]*resolution, len(current)+1)
+	if len(current) < maxResolutions {
+		for key, val := range current {
+			next[key] = val
+		}
+	}
+	next[l] = r
+	c.resolutions.Store(next)
+
+	return r
+}
+
+// drops the cached resolutions, once fallbacks or providers have changed
+func (c *Catalog) forgetResolutions() {
+	c.resolutionLock.Lock()
+	defer c.resolutionLock.Unlock()
+
+	c.resolutionEpoch++
+	c.resolutions.Store(make(map[Locale]*resolution))
+}
+
+// activates a locale and its fallbacks, and returns their canonical codes in lookup order. The
+// codes must not be modified. Bundles of providers implementing BundleLister are loaded as far as
+// the scopes need them. Fails with the first activation error (which can only occur in strict
+// mode), or once the context is done
+func (c *Catalog) activate(ctx context.Context, l Locale, scopes ...internal.Scope) (string, []string, error) {
+	r := c.resolve(l)
+
+	_, err := c.universe.ActivateContext(ctx, r.locale, scopes...)
+	for _, code := range r.codes {
+		if ctx.Err() != nil {
+			return r.locale, r.codes, ctx.Err()
+		}
+
+		if _, nextErr := c.universe.ActivateContext(ctx, code, scopes...); err == nil {
+			err = nextErr
+		}
+	}
+
+	return r.locale, r.codes, err
+}
+
+// returns the scope of a lookup of a key, and with recurse, of its parent keys as well
+func keyScopes(key string, recurse bool) []internal.Scope {
+	scopes := []internal.Scope{}
+	for hierarchy := types.HierarchicalKey(key); ; {
+		prefix, _ := hierarchy.Split()
+		scopes = append(scopes, internal.Scope{prefix, false})
+
+		if hierarchy = hierarchy.Parent(); !recurse || hierarchy.String() == "" {
+			return scopes
+		}
+	}
+}
+
+// returns the scope of a bundle, and with recurse, of its parent bundles as well
+func bundleScopes(prefix string, recurse bool) []internal.Scope {
+	scopes := []internal.Scope{{prefix, false}}
+	for hierarchy := types.HierarchicalKey(prefix); recurse && hierarchy.String() != ""; {
+		hierarchy = hierarchy.Parent()
+		scopes = append(scopes, internal.Scope{hierarchy.String(), false})
+	}
+
+	return scopes
+}
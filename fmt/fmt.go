@@ -19,6 +19,7 @@ package fmt
 
 import (
 	"bytes"
+	"errors"
 	i18n "github.com/beatgammit/ginta"
 	"github.com/beatgammit/ginta/common"
 	sysfmt "fmt"
@@ -141,7 +142,8 @@ func (err *errorResource) LocalError(loc i18n.Locale) string {
 	if str, lookupErr = loc.GetResource(err.key); err == nil {
 		str = ApplyFormat(loc, str, err.arguments...)
 	} else {
-		if notFound, ok := lookupErr.(common.ResourceNotFoundError); ok {
+		var notFound common.ResourceNotFoundError
+		if errors.As(lookupErr, &notFound) {
 			if notFoundTemplate, err2 := loc.GetResource(common.ResourceNotFoundResourceKey); err2 == nil {
 				str = ApplyFormat(loc, notFoundTemplate, string(notFound))
 			} else {
@@ -172,9 +174,18 @@ func ApplyFormat(locale i18n.Locale, template string, args ...interface{}) strin
 
 /*
 Compiles a format template into a format ready for execution. The
-result may be saved and executed any number of times
+result may be saved and executed any number of times. Formats are
+looked up in the default catalog.
 */
 func Compile(template string) (*MessageFormat, error) {
+	return CompileIn(i18n.DefaultCatalog, template)
+}
+
+/*
+Compiles a format template, looking up formats in the registry of the
+specified catalog
+*/
+func CompileIn(c *i18n.Catalog, template string) (*MessageFormat, error) {
 	formatString := new(bytes.Buffer)
 	argumentString := new(bytes.Buffer)
 	buffer := formatString
@@ -197,7 +208,7 @@ func Compile(template string) (*MessageFormat, error) {
 			if buffer == argumentString {
 				argumentDefinition := buffer.String()
 				buffer.Reset()
-				idx, input, err := parseArgument(argumentDefinition)
+				idx, input, err := parseArgument(c, argumentDefinition)
 
 				if err != nil {
 					return nil, err
@@ -223,7 +234,7 @@ func Compile(template string) (*MessageFormat, error) {
 	return &MessageFormat{formatString.String(), argumentMapping, converterMapping}, nil
 }
 
-func parseArgument(def string) (int, MessageInput, error) {
+func parseArgument(c *i18n.Catalog, def string) (int, MessageInput, error) {
 
 	if parts := strings.Split(def, FormatSegmentSeparator); len(parts) > 0 {
 		for i, val := range parts {
@@ -233,7 +244,7 @@ func parseArgument(def string) (int, MessageInput, error) {
 		if pos, err := strconv.Atoi(parts[0]); err == nil {
 			if len(parts) > 1 {
 				formatterName := parts[1]
-				if factory, ok := registry[formatterName]; ok {
+				if factory, ok := lookupFormat(c, formatterName); ok {
 					result, err := factory.Compile(parts[2:])
 					return pos, result, err
 				} else {
@@ -248,8 +259,6 @@ func parseArgument(def string) (int, MessageInput, error) {
 	return -1, nil, NewError(BadFormatResourceKey, def)
 }
 
-var registry map[string]FormatDefinition = make(map[string]FormatDefinition)
-
 // Message formatters implement this interface.
 type FormatDefinition interface {
 	// given any extra arguments (after variable nr and format name), init a MessageInput
@@ -264,7 +273,21 @@ func (f FormatDefinitionFunc) Compile(args []string) (MessageInput, error) {
 	return f(args)
 }
 
-// Registers a new format, given a unique name and a parser callback
+// Registers a new format in the default catalog, given a unique name and a parser callback
 func RegisterFormat(name string, def FormatDefinition) {
-	registry[name] = def
+	RegisterCatalogFormat(i18n.DefaultCatalog, name, def)
+}
+
+// Registers a new format in the registry of a catalog
+func RegisterCatalogFormat(c *i18n.Catalog, name string, def FormatDefinition) {
+	c.RegisterFormat(name, def)
+}
+
+func lookupFormat(c *i18n.Catalog, name string) (FormatDefinition, bool) {
+	if def, ok := c.Format(name); ok {
+		factory, ok := def.(FormatDefinition)
+		return factory, ok
+	}
+
+	return nil, false
 }
@@ -114,3 +114,23 @@ func TestRegisterNewFormatter(t *testing.T) {
 		t.Error(str, fmt)
 	}
 }
+
+func TestCatalogFormatRegistry(t *testing.T) {
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	RegisterCatalogFormat(c, "catalogOnly", t1Fmt{})
+
+	if _, err := Compile("{0,catalogOnly}"); err == nil {
+		t.Error("format leaked into the default catalog")
+	}
+
+	fmt, err := CompileIn(c, "{0,catalogOnly}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if str := fmt.Format(ginta.DefaultLocale, "abc"); str != customFormatResult {
+		t.Error(str)
+	}
+}
@@ -22,6 +22,7 @@ package nr
 
 import (
 	"bytes"
+	"github.com/beatgammit/ginta"
 	"github.com/beatgammit/ginta/fmt"
 	"strconv"
 )
@@ -38,7 +39,12 @@ const (
 // installs this format - should be called at the very start of the program, prior to registring
 // the first provider.
 func Install() {
-	fmt.RegisterFormat(Format, fmt.FormatDefinitionFunc(parse))
+	InstallIn(ginta.DefaultCatalog)
+}
+
+// installs this format in the registry of a catalog
+func InstallIn(c *ginta.Catalog) {
+	fmt.RegisterCatalogFormat(c, Format, fmt.FormatDefinitionFunc(parse))
 }
 
 func parse(args []string) (fmt.MessageInput, error) {
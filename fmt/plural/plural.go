@@ -93,7 +93,11 @@ const (
 	nothingFoundGlobal      = 0x7fffffff
 )
 
-type pluralStem string
+// a plural bundle, looked up in the catalog the format was installed in
+type pluralStem struct {
+	catalog *ginta.Catalog
+	path    string
+}
 
 // Implement this to allow your custom type to be used as a value
 type IntValuer interface {
@@ -108,12 +112,23 @@ type FloatValuer interface {
 // installs this format - should be called at the very start of the program, prior to registring
 // the first provider.
 func Install() {
-	fmt.RegisterFormat(Format, fmt.FormatDefinitionFunc(parse))
+	InstallIn(ginta.DefaultCatalog)
+}
+
+// installs this format in the registry of a catalog. Plural bundles are looked up in that catalog
+func InstallIn(c *ginta.Catalog) {
+	fmt.RegisterCatalogFormat(c, Format, fmt.FormatDefinitionFunc(func(args []string) (fmt.MessageInput, error) {
+		return parseIn(c, args)
+	}))
 }
 
 func parse(args []string) (fmt.MessageInput, error) {
+	return parseIn(ginta.DefaultCatalog, args)
+}
+
+func parseIn(c *ginta.Catalog, args []string) (fmt.MessageInput, error) {
 	if len(args) == 1 {
-		return pluralStem(args[0]), nil
+		return pluralStem{c, args[0]}, nil
 	}
 	return nil, fmt.NewError(fmt.MalformedFormatSpecificationErrorResourceKey, args)
 }
@@ -123,8 +138,8 @@ func (p pluralStem) Converter() fmt.Converter {
 }
 
 func (p pluralStem) Convert(l ginta.Locale, input interface{}) interface{} {
-	base := PluralStemResourcesPath + string(p)
-	bundle := l.GetResourceBundle(base)
+	base := PluralStemResourcesPath + p.path
+	bundle := p.catalog.GetResourceBundle(l, base)
 
 	if f, convert := value(input); convert {
 		priority := nothingFoundGlobal
@@ -3,6 +3,7 @@ package plural
 import (
 	"bytes"
 	"github.com/beatgammit/ginta"
+	"github.com/beatgammit/ginta/fmt"
 	"github.com/beatgammit/ginta/providers/simple"
 	"testing"
 )
@@ -220,3 +221,26 @@ func TestInputTypes(t *testing.T) {
 		t.Error(p, err)
 	}
 }
+
+func TestInstallIn(t *testing.T) {
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	InstallIn(c)
+	c.Register(simple.New().AddLanguage("lc", "Catalog only", map[string]string{
+		"plurals:t0:eq1":     "one",
+		"plurals:t0:default": "many",
+	}))
+
+	format, err := fmt.CompileIn(c, "{0,plural,t0}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if str := format.Format(ginta.Locale("lc"), 1); str != "one" {
+		t.Error(str)
+	}
+	if str := format.Format(ginta.Locale("lc"), 2); str != "many" {
+		t.Error(str)
+	}
+}
@@ -2,6 +2,7 @@
 package quoted
 
 import (
+	"github.com/beatgammit/ginta"
 	"github.com/beatgammit/ginta/fmt"
 )
 
@@ -11,7 +12,12 @@ const FormatName = "quoted"
 // installs this format - should be called at the very start of the program, prior to registring
 // the first provider.
 func Install() {
-	fmt.RegisterFormat(FormatName, fmt.FormatDefinitionFunc(parse))
+	InstallIn(ginta.DefaultCatalog)
+}
+
+// installs this format in the registry of a catalog
+func InstallIn(c *ginta.Catalog) {
+	fmt.RegisterCatalogFormat(c, FormatName, fmt.FormatDefinitionFunc(parse))
 }
 
 func parse(args []string) (fmt.MessageInput, error) {
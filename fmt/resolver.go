@@ -14,15 +14,23 @@ type Resolver struct {
 	// The base path relative to which all resources will be located. 
 	Base string
 	// This type contains unexported fields
-	locale i18n.Locale
-	cache  map[string]*MessageFormat
+	catalog *i18n.Catalog
+	locale  i18n.Locale
+	cache   map[string]*MessageFormat
 }
 
 /*
 Initializes a new resolver with the specified locale, and base path
 */
 func NewResolver(locale i18n.Locale, base string) *Resolver {
-	return &Resolver{base, locale, map[string]*MessageFormat{}}
+	return NewCatalogResolver(i18n.DefaultCatalog, locale, base)
+}
+
+/*
+Initializes a new resolver that retrieves its resources and formats from the specified catalog
+*/
+func NewCatalogResolver(c *i18n.Catalog, locale i18n.Locale, base string) *Resolver {
+	return &Resolver{base, c, locale, map[string]*MessageFormat{}}
 }
 
 /*
@@ -34,8 +42,8 @@ func (r *Resolver) Format(key string, args ...interface{}) (result string, err e
 	fmt, ok := r.cache[key]
 	if !ok {
 		var str string
-		if str, err = r.locale.GetResource(key); err == nil {
-			fmt, err = Compile(str)
+		if str, err = r.catalog.GetResource(r.locale, key); err == nil {
+			fmt, err = CompileIn(r.catalog, str)
 			r.cache[key] = fmt
 		} else {
 			return
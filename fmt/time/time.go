@@ -50,6 +50,20 @@ const (
 type dateFormatType string
 
 func (typ dateFormatType) Compile(args []string) (fmt.MessageInput, error) {
+	return typ.compileIn(ginta.DefaultCatalog, args)
+}
+
+// a date format type installed in a catalog, whose resources are looked up in that catalog
+type catalogFormatType struct {
+	catalog *ginta.Catalog
+	typ     dateFormatType
+}
+
+func (c catalogFormatType) Compile(args []string) (fmt.MessageInput, error) {
+	return c.typ.compileIn(c.catalog, args)
+}
+
+func (typ dateFormatType) compileIn(c *ginta.Catalog, args []string) (fmt.MessageInput, error) {
 	l := len(args)
 	var res string
 
@@ -60,7 +74,7 @@ func (typ dateFormatType) Compile(args []string) (fmt.MessageInput, error) {
 	}
 
 	if res != "" {
-		return dateFormat(res), nil
+		return dateFormat{c, res}, nil
 	}
 
 	return nil, fmt.NewError(fmt.MalformedFormatSpecificationErrorResourceKey, args)
@@ -68,12 +82,22 @@ func (typ dateFormatType) Compile(args []string) (fmt.MessageInput, error) {
 
 // Registers the date formats with the format package
 func Install() {
-	fmt.RegisterFormat(DateFormat, dateFormatType(DateFormat))
-	fmt.RegisterFormat(TimeFormat, dateFormatType(TimeFormat))
-	fmt.RegisterFormat(DateTimeFormat, dateFormatType(DateTimeFormat))
+	InstallIn(ginta.DefaultCatalog)
 }
 
-type dateFormat string
+// Registers the date formats in the registry of a catalog. The formats and substitutions are
+// looked up in that catalog
+func InstallIn(c *ginta.Catalog) {
+	fmt.RegisterCatalogFormat(c, DateFormat, catalogFormatType{c, DateFormat})
+	fmt.RegisterCatalogFormat(c, TimeFormat, catalogFormatType{c, TimeFormat})
+	fmt.RegisterCatalogFormat(c, DateTimeFormat, catalogFormatType{c, DateTimeFormat})
+}
+
+// the resource key of a format, and the catalog it is looked up in
+type dateFormat struct {
+	catalog *ginta.Catalog
+	key     string
+}
 
 func (d dateFormat) FormatString() string {
 	return "%v"
@@ -85,7 +109,7 @@ func (d dateFormat) Converter() fmt.Converter {
 
 func (d dateFormat) Convert(locale ginta.Locale, arg interface{}) interface{} {
 	if time, ok := arg.(time.Time); ok {
-		return EvaluateFormat(common.HierarchicalKey(d), locale, time)
+		return EvaluateFormatIn(d.catalog, common.HierarchicalKey(d.key), locale, time)
 	}
 
 	return arg
@@ -94,11 +118,16 @@ func (d dateFormat) Convert(locale ginta.Locale, arg interface{}) interface{} {
 // Evaluates the format stored under the provided hierarchical key, performing formatting and substitutions
 // as defined in the current locale
 func EvaluateFormat(format common.HierarchicalKey, locale ginta.Locale, instant time.Time) string {
-	fmtString, err := locale.ResolveResource(format)
+	return EvaluateFormatIn(ginta.DefaultCatalog, format, locale, instant)
+}
+
+// Like EvaluateFormat, but looks up the format and substitutions in a catalog
+func EvaluateFormatIn(c *ginta.Catalog, format common.HierarchicalKey, locale ginta.Locale, instant time.Time) string {
+	fmtString, err := c.ResolveResource(locale, format)
 	if err == nil {
 		result := instant.Format(fmtString)
 
-		bundle := locale.ResolveResourceBundle(SubstitutionsResourceBundle)
+		bundle := c.ResolveResourceBundle(locale, SubstitutionsResourceBundle)
 
 		// now perform substitutions for strings (wednesday -> miércoles)
 		for from, to := range bundle {
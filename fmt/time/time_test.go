@@ -2,7 +2,7 @@ package time
 
 import (
 	"github.com/beatgammit/ginta"
-	"github.com/beatgammit/ginta/internal"
+	"github.com/beatgammit/ginta/fmt"
 	"github.com/beatgammit/ginta/providers/simple"
 	"testing"
 	"time"
@@ -14,7 +14,6 @@ func en() {
 	})
 
 	ginta.Register(p)
-	internal.Activate("en")
 }
 
 func de() {
@@ -25,7 +24,6 @@ func de() {
 	})
 
 	ginta.Register(p)
-	internal.Activate("de")
 }
 
 func TestSimpleDate(t *testing.T) {
@@ -67,3 +65,24 @@ func TestLongWithSubstitutions(t *testing.T) {
 		t.Error(conv)
 	}
 }
+
+func TestInstallIn(t *testing.T) {
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	InstallIn(c)
+	c.Register(simple.New().AddLanguage("tc", "Catalog only", map[string]string{
+		TimeFormatRoot + ":" + DateFormat + ":" + OptionShort: "Monday 02.01.",
+		SubstitutionsResourceBundle + ":Monday":               "Montag",
+	}))
+
+	format, err := fmt.CompileIn(c, "{0,date,short}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	example := time.Date(2013, 5, 20, 17, 25, 29, 0, time.UTC)
+	if str := format.Format(ginta.Locale("tc"), example); str != "Montag 20.05." {
+		t.Error(str)
+	}
+}
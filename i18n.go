@@ -13,14 +13,21 @@
 	hierarchical key, the system automatically walks through the super-keys until either a matching resource is located,
 	or resolution fails even on the "root key", in which case an error is returned.
 
+	Both modes of query honor the fallback chain of a locale (See Locale.Fallbacks). Only after a lookup has
+	failed within a language are its fallback languages consulted, so regional languages (de-AT) need only
+	contain the resources that differ from their base language (de).
+
+	Languages are kept in a Catalog. The package-level functions and the methods of Locale operate
+	on the DefaultCatalog, applications needing separate translation sets create their own catalogs.
+
 	This package contains the basic primitive functions of ginta. These functions are used to query the translation database for resource 
 	entries, either individually or in bulk.	
 */
 package ginta
 
 import (
+	"context"
 	types "github.com/beatgammit/ginta/common"
-	"github.com/beatgammit/ginta/internal"
 )
 
 /*
@@ -53,7 +60,56 @@ type LanguageProvider interface {
 }
 
 /*
-	Locale defines methods to access resources for a language
+	Enumerators that can fail may implement this interface in addition to Enumerator. EnumerateReporting
+	behaves like Enumerate, but passes every failure (such as an unreadable directory) to the report
+	function. All failures must be reported before the returned channel is closed.
+*/
+type ReportingEnumerator interface {
+	EnumerateReporting(report func(error)) <-chan types.Language
+}
+
+/*
+	Listers that can fail may implement this interface in addition to Lister. ListReporting behaves like
+	List, but passes every failure (such as an unreadable file, or a malformed line) to the report function.
+	Reporting a *common.LoadError allows the provider to state the file and line that failed. All failures
+	must be reported before the returned channel is closed.
+*/
+type ReportingLister interface {
+	ListReporting(code string, report func(error)) <-chan types.Resource
+}
+
+/*
+	Listers that know where their resources are defined may implement this interface in addition to
+	Lister. ListLocated behaves like ListReporting, but states the file and line of each resource. The
+	catalog uses them to describe resources defined more than once (See Catalog.Conflicts).
+*/
+type LocatingLister interface {
+	ListLocated(code string, report func(error)) <-chan types.LocatedResource
+}
+
+/*
+	Providers of large catalogs may implement this interface in addition to Lister, to have their
+	resources loaded by bundle: the catalog then loads only the bundles lookups actually need, once
+	they need them, instead of all resources of a language on activation. Lookups see the same
+	resources either way.
+
+	Bundles lists the prefixes of the bundles of a language, the empty prefix standing for the
+	root bundle. ListBundle lists the resources of a single bundle, which must all be within its
+	prefix (any key is within the root bundle). Where bundles define the same key, the bundle with
+	the longer prefix wins, regardless of the order the bundles are loaded in: a file of the bundle
+	"menu" takes precedence over "menu:x" in a file of the root bundle. Both must report failures
+	like ReportingLister does. Listing a bundle, for example, may read the files of a single
+	directory (See providers/fs). Bundles are listed on registration, and again on Reload.
+*/
+type BundleLister interface {
+	Bundles(code string, report func(error)) []string
+	ListBundle(code, prefix string, report func(error)) <-chan types.LocatedResource
+}
+
+/*
+	Locale defines methods to access resources for a language. A locale is identified by
+	its BCP 47 language tag (See common.Tag). Codes are canonicalized before use, so
+	"en_US", "en-us" and "EN-US" all denote the same locale.
 */
 type Locale string
 
@@ -64,53 +120,180 @@ type Locale string
 var DefaultLocale Locale = Locale("en")
 
 /*
-	Adds a language provider to the system. Often there will be 
+	Adds a language provider to the DefaultCatalog. Often there will be 
 	a single provider, but there may be more. In case of multiple
 	providers defining the same language, their definitions will
 	be merged (that is, their resource sets combined). In this case,
 	providers registered later will overwrite these defined
-	earlier. 
+	earlier, no matter in which order the providers deliver their
+	resources. See RegisterWithPriority to rank providers explicitly.
+
+	The language codes of the provider are canonicalized (See Locale), 
+	but the provider is still queried with its own codes.
+
+	Returns the errors reported while enumerating the languages of the
+	provider (See ReportingEnumerator) as common.LoadErrors. The languages
+	that could be enumerated are registered nevertheless.
+*/
+func Register(p LanguageProvider) error {
+	return DefaultCatalog.Register(p)
+}
+
+/*
+	Adds a language provider to the DefaultCatalog with an explicit
+	priority. Providers with a higher priority overwrite the resources
+	of providers with a lower one, whatever the order of registration.
+	Register uses priority 0.
+*/
+func RegisterWithPriority(p LanguageProvider, priority int) error {
+	return DefaultCatalog.RegisterWithPriority(p, priority)
+}
+
+/*
+	Removes a language provider from the DefaultCatalog, rebuilding the
+	locales it contributed to from the remaining providers. Providers are
+	identified by == (See Catalog.Unregister).
+*/
+func Unregister(p LanguageProvider) {
+	DefaultCatalog.Unregister(p)
+}
+
+/*
+	Subscribes to the changes of the resources of the DefaultCatalog (See
+	Catalog.Watch). Calling the returned function ends the subscription.
+*/
+func Watch(handler func(types.Change)) (stop func()) {
+	return DefaultCatalog.Watch(handler)
+}
+
+/*
+	Fetches the resources of a locale of the DefaultCatalog again, and
+	replaces them atomically once all providers have delivered (See
+	Catalog.Reload).
+*/
+func Reload(l Locale) error {
+	return DefaultCatalog.Reload(l)
+}
+
+/*
+	Loads all resources of this locale and its fallback chain ahead of use. Lookups activate
+	their locale automatically, so calling this is optional. Lookups load only the bundles they
+	need from providers implementing BundleLister, while Activate loads them all. Fails only in
+	strict mode (See Catalog.SetStrict), if errors were reported while loading.
 */
-func Register(p LanguageProvider) {
-	internal.Register(p.Enumerate(), func(code string) <-chan types.Resource {
-		resource := p.List(code)
-		return resource
-	})
+func (l Locale) Activate() error {
+	return DefaultCatalog.Activate(l)
 }
 
 /*
-	Lists all currently known languages 
+	Like Activate, but gives up waiting once the context is done. See Catalog.ActivateContext
+*/
+func (l Locale) ActivateContext(ctx context.Context) error {
+	return DefaultCatalog.ActivateContext(ctx, l)
+}
+
+/*
+	Returns the canonical form of this locale. Locales that are not well-formed
+	language tags are returned unchanged.
+*/
+func (l Locale) Canonical() Locale {
+	return Locale(types.Canonicalize(string(l)))
+}
+
+/*
+	Parses this locale into its language tag. Fails if the locale is not a well-formed
+	language tag.
+*/
+func (l Locale) Tag() (types.Tag, error) {
+	return types.ParseTag(string(l))
+}
+
+/*
+	Lists all currently known languages of the DefaultCatalog, by their canonical codes,
+	with their properties such as native name, text direction and plural rules
 */
 func List() []*types.Language {
-	return internal.List()
+	return DefaultCatalog.List()
+}
+
+/*
+	Returns the description of this locale in the DefaultCatalog, if a provider offers it
+*/
+func (l Locale) Language() (types.Language, bool) {
+	return DefaultCatalog.Language(l)
 }
 
 /*
 	Resolves a resource by its hierarchical key. 
 */
 func (l Locale) ResolveResource(k types.HierarchicalKey) (string, error) {
-	locale := string(l)
-	internal.Activate(locale)
-	return internal.Request(locale, string(k), true)
+	return DefaultCatalog.ResolveResource(l, k)
+}
+
+/*
+	Returns where the resource returned by GetResource for the key is defined, so that tools
+	and error messages can point translators to the file to fix. See Catalog.Origin
+*/
+func (l Locale) Origin(key string) (types.Origin, bool) {
+	return DefaultCatalog.Origin(l, key)
+}
+
+/*
+	Returns the metadata of the resource returned by GetResource for the key, such as its
+	description for translators. See Catalog.Metadata
+*/
+func (l Locale) Metadata(key string) (types.Metadata, bool) {
+	return DefaultCatalog.Metadata(l, key)
+}
+
+/*
+	Returns the resources of this locale below the prefix, with their origin and metadata.
+	See Catalog.Export
+*/
+func (l Locale) Export(prefix string) []types.LocatedResource {
+	return DefaultCatalog.Export(l, prefix)
+}
+
+/*
+	Traces the resolution of a resource by its hierarchical key, for debugging surprising
+	results. The trace lists every key checked (See common.HierarchicalKey.Parent), for this
+	locale and each locale of its fallback chain, and the provider, file and line of the match.
+	Failed lookups return a *common.NotFoundError carrying the same list of candidates.
+*/
+func (l Locale) Trace(k types.HierarchicalKey) types.Trace {
+	return DefaultCatalog.Trace(l, k)
+}
+
+/*
+	Resolves a resource by its hierarchical key, giving up with the error of the context if
+	the locale cannot be activated before the context is done
+*/
+func (l Locale) ResolveResourceContext(ctx context.Context, k types.HierarchicalKey) (string, error) {
+	return DefaultCatalog.ResolveResourceContext(ctx, l, k)
 }
 
 /*
 	Returns a resource by simple name matching
 */
 func (l Locale) GetResource(key string) (string, error) {
-	locale := string(l)
-	internal.Activate(locale)
-	return internal.Request(locale, key, false)
+	return DefaultCatalog.GetResource(l, key)
+}
+
+/*
+	Returns a resource by simple name matching, giving up with the error of the context if
+	the locale cannot be activated before the context is done
+*/
+func (l Locale) GetResourceContext(ctx context.Context, key string) (string, error) {
+	return DefaultCatalog.GetResourceContext(ctx, l, key)
 }
 
 /*
 	Returns a "resource bundle". This bundle is contains all resource whose hierarchical key has
-	exactly the specified prefix - but no resources with shorter or longer prefix paths.
+	exactly the specified prefix - but no resources with shorter or longer prefix paths. Resources
+	missing in this locale are taken from its fallback chain.
 */
 func (l Locale) GetResourceBundle(prefix string) map[string]string {
-	locale := string(l)
-	internal.Activate(locale)
-	return internal.RequestBundle(locale, prefix, false)
+	return DefaultCatalog.GetResourceBundle(l, prefix)
 }
 
 /*
@@ -118,7 +301,5 @@ func (l Locale) GetResourceBundle(prefix string) map[string]string {
 	defined in a child are not overwritten by its parent.
 */
 func (l Locale) ResolveResourceBundle(prefix string) map[string]string {
-	locale := string(l)
-	internal.Activate(locale)
-	return internal.RequestBundle(locale, prefix, true)
+	return DefaultCatalog.ResolveResourceBundle(l, prefix)
 }
@@ -1,6 +1,8 @@
 package ginta
 
 import (
+	"context"
+	"errors"
 	types "github.com/beatgammit/ginta/common"
 	"reflect"
 	"testing"
@@ -20,7 +22,7 @@ func (m mockProviderEmpty) Enumerate() <-chan types.Language {
 	c := make(chan types.Language)
 
 	go func() {
-		c <- types.Language{string(m), string(m)}
+		c <- types.Language{Code: string(m), DisplayName: string(m)}
 		close(c)
 	}()
 
@@ -38,7 +40,7 @@ func (m *mockProviderSingle) Enumerate() <-chan types.Language {
 	c := make(chan types.Language)
 
 	go func() {
-		c <- types.Language{m.code, m.code}
+		c <- types.Language{Code: m.code, DisplayName: m.code}
 		close(c)
 	}()
 
@@ -60,7 +62,7 @@ func (m *mockProviderMap) Enumerate() <-chan types.Language {
 	c := make(chan types.Language)
 
 	go func() {
-		c <- types.Language{m.code, m.code}
+		c <- types.Language{Code: m.code, DisplayName: m.code}
 		close(c)
 	}()
 
@@ -116,7 +118,8 @@ func TestRegisterDoesNotBleedOver(t *testing.T) {
 		t.Error(result, err)
 	}
 
-	if _, ok := err.(types.ResourceNotFoundError); !ok {
+	var notFound types.ResourceNotFoundError
+	if !errors.As(err, &notFound) || notFound != "key1" || !errors.Is(err, types.ErrResourceNotFound) {
 		t.Error(reflect.TypeOf(err))
 	}
 }
@@ -277,3 +280,121 @@ func TestListHierarchical(t *testing.T) {
 		}
 	}
 }
+
+func TestDerivedFallbacks(t *testing.T) {
+	expect := []Locale{"de-CH", "de", DefaultLocale}
+
+	if chain := Locale("de-CH-1996").Fallbacks(); !reflect.DeepEqual(chain, expect) {
+		t.Error(chain)
+	}
+
+	if chain := DefaultLocale.Fallbacks(); len(chain) != 0 {
+		t.Error(chain)
+	}
+}
+
+func TestOverriddenFallbacks(t *testing.T) {
+	SetFallbacks("es-MX", "es-419", "es")
+	defer SetFallbacks("es-MX")
+
+	expect := []Locale{"es-419", "es", DefaultLocale}
+	if chain := Locale("es-MX").Fallbacks(); !reflect.DeepEqual(chain, expect) {
+		t.Error(chain)
+	}
+}
+
+func TestFallbackLookup(t *testing.T) {
+	Register(&mockProviderMap{"fb", map[string]string{
+		"x":      "base",
+		"a:y":    "base y",
+		"a:z":    "base z",
+		"only":   "base only",
+		"menu:x": "base menu",
+	}})
+	Register(&mockProviderMap{"fb-at", map[string]string{
+		"a:y": "regional y",
+	}})
+
+	l := Locale("fb-at")
+
+	if str, err := l.GetResource("a:y"); err != nil || str != "regional y" {
+		t.Error(str, err)
+	}
+
+	if str, err := l.GetResource("only"); err != nil || str != "base only" {
+		t.Error(str, err)
+	}
+
+	// hierarchical resolution within fb-at fails, so fb must be consulted before its root key
+	if str, err := l.ResolveResource("menu:x"); err != nil || str != "base menu" {
+		t.Error(str, err)
+	}
+
+	expect := map[string]string{"y": "regional y", "z": "base z"}
+	if bundle := l.GetResourceBundle("a"); !reflect.DeepEqual(bundle, expect) {
+		t.Error(bundle)
+	}
+}
+
+type mockProviderCodes map[string]string
+
+func (m mockProviderCodes) Enumerate() <-chan types.Language {
+	c := make(chan types.Language)
+
+	go func() {
+		for code := range m {
+			c <- types.Language{Code: code, DisplayName: code}
+		}
+		close(c)
+	}()
+
+	return c
+}
+
+func (m mockProviderCodes) List(code string) <-chan types.Resource {
+	c := make(chan types.Resource)
+
+	go func() {
+		c <- types.Resource{"code", code}
+		c <- types.Resource{code, m[code]}
+		close(c)
+	}()
+
+	return c
+}
+
+func TestCanonicalCodes(t *testing.T) {
+	Register(mockProviderCodes{"cc_XA": "underscore", "cc-xa": "lower"})
+
+	found := false
+	for _, lang := range List() {
+		if lang.Code == "cc_XA" || lang.Code == "cc-xa" {
+			t.Error(lang)
+		}
+		found = found || lang.Code == "cc-XA"
+	}
+
+	if !found {
+		t.Error(List())
+	}
+
+	l := Locale("CC-xa")
+	for code, val := range map[string]string{"cc_XA": "underscore", "cc-xa": "lower"} {
+		if str, err := l.GetResource(code); err != nil || str != val {
+			t.Error(str, err)
+		}
+	}
+}
+
+func TestLocaleContext(t *testing.T) {
+	ctx := context.Background()
+
+	if l, ok := FromContext(ctx); ok || LocaleFromContext(ctx) != DefaultLocale {
+		t.Error(l, ok)
+	}
+
+	ctx = NewContext(ctx, "de-AT")
+	if l, ok := FromContext(ctx); !ok || l != "de-AT" || LocaleFromContext(ctx) != "de-AT" {
+		t.Error(l, ok)
+	}
+}
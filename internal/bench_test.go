@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"github.com/beatgammit/ginta/common"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// The actor design the snapshot design replaced: every lookup is a round-trip through
+// a single goroutine, over unbuffered channels. Kept for comparison in benchmarks only
+type actor struct {
+	requests chan actorRequest
+	entries  map[string]bundle
+}
+
+type actorRequest struct {
+	key   string
+	reply chan string
+}
+
+func newActor(entries map[string]bundle) *actor {
+	a := &actor{make(chan actorRequest), entries}
+
+	go func() {
+		for request := range a.requests {
+			prefix, key := common.HierarchicalKey(request.key).Split()
+			request.reply <- a.entries[prefix][key].value
+		}
+	}()
+
+	return a
+}
+
+func (a *actor) request(key string) string {
+	reply := make(chan string)
+	a.requests <- actorRequest{key, reply}
+	return <-reply
+}
+
+const benchmarkKeys = 1000
+
+func benchmarkResources() map[string]string {
+	m := make(map[string]string)
+	for i := 0; i < benchmarkKeys; i++ {
+		m["bundle"+strconv.Itoa(i%10)+":key"+strconv.Itoa(i)] = "value" + strconv.Itoa(i)
+	}
+
+	return m
+}
+
+func benchmarkKey(i int) string {
+	i %= benchmarkKeys
+	return "bundle" + strconv.Itoa(i%10) + ":key" + strconv.Itoa(i)
+}
+
+func benchmarkUniverse(b *testing.B) *Universe {
+	u := New()
+	l := make(chan common.Language, 1)
+	l <- common.Language{Code: "b1", DisplayName: "Benchmark"}
+	close(l)
+
+	u.Register(nil, l, sendMap(nil, benchmarkResources()))
+	u.Activate("b1")
+
+	return u
+}
+
+func benchmarkActor() *actor {
+	entries := make(map[string]bundle)
+	for k, v := range benchmarkResources() {
+		prefix, key := common.HierarchicalKey(k).Split()
+		if entries[prefix] == nil {
+			entries[prefix] = make(bundle)
+		}
+		entries[prefix][key] = entry{v, nil, 0, 0}
+	}
+
+	return newActor(entries)
+}
+
+func BenchmarkSnapshotRequest(b *testing.B) {
+	u := benchmarkUniverse(b)
+	defer u.Close()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		u.Activate("b1")
+		u.Request("b1", benchmarkKey(i), false)
+	}
+}
+
+func BenchmarkSnapshotRequestParallel(b *testing.B) {
+	u := benchmarkUniverse(b)
+	defer u.Close()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			u.Activate("b1")
+			u.Request("b1", benchmarkKey(i), false)
+		}
+	})
+}
+
+func BenchmarkActorRequest(b *testing.B) {
+	a := benchmarkActor()
+	defer close(a.requests)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		a.request(benchmarkKey(i))
+		a.request(benchmarkKey(i))
+	}
+}
+
+func BenchmarkActorRequestParallel(b *testing.B) {
+	a := benchmarkActor()
+	defer close(a.requests)
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			// one round-trip for the activation, one for the request
+			a.request(benchmarkKey(i))
+			a.request(benchmarkKey(i))
+		}
+	})
+}
+
+// The synthetic catalog of the memory benchmarks: a million keys in a thousand bundles, defined in
+// every language, each bundle in a file of its own
+const (
+	catalogKeys    = 1000000
+	catalogBundles = 1000
+)
+
+// fetches the synthetic catalog. Keys and origins are built anew for every language, as a provider
+// parsing files would
+func sendCatalog(code string, _ func(error)) <-chan common.LocatedResource {
+	c := make(chan common.LocatedResource, 1024)
+	go func() {
+		defer close(c)
+		for i := 0; i < catalogKeys; i++ {
+			bundle := "bundle" + strconv.Itoa(i%catalogBundles)
+			origin := common.Origin{"synthetic", "/" + code + "/" + bundle + ".txt", i/catalogBundles + 1}
+			c <- common.LocatedResource{common.Resource{bundle + ":key" + strconv.Itoa(i), code + strconv.Itoa(i)}, origin, nil}
+		}
+	}()
+
+	return c
+}
+
+// reports the heap retained per resource by a universe holding the synthetic catalog in the languages
+func benchmarkCatalogMemory(b *testing.B, languages int) {
+	var before, after runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		u := New()
+		for n := 0; n < languages; n++ {
+			l := make(chan common.Language, 1)
+			l <- common.Language{Code: "m" + strconv.Itoa(n), DisplayName: "Memory"}
+			close(l)
+
+			u.Register(nil, l, sendCatalog)
+			u.Activate("m" + strconv.Itoa(n))
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		if v, _ := u.Request("m0", "bundle1:key1", false); v != "m01" {
+			b.Fatal(v)
+		}
+		u.Close()
+	}
+
+	// signed, as the heap may shrink below where it was when garbage of earlier runs is collected
+	retained := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	b.ReportMetric(float64(retained)/float64(catalogKeys*languages), "B/resource")
+}
+
+func BenchmarkCatalogMemory1(b *testing.B) {
+	benchmarkCatalogMemory(b, 1)
+}
+
+func BenchmarkCatalogMemory4(b *testing.B) {
+	benchmarkCatalogMemory(b, 4)
+}
@@ -2,281 +2,1575 @@
 Internal workhorse of ginta. This package contains
 few functions of interest to the user, but isolates the
 core from the rest of the system to avoid accidental
-misuse. 
+misuse.
 
-It implements an actor that moderates all access to 
-language resources. 
+Each Universe is an independent set of languages. Reads
+never block: the resources of a language are kept in an
+immutable snapshot, which is replaced atomically whenever
+resources are loaded or updated (copy-on-write). Writers
+are serialized by a lock, and never disturb running reads.
 */
 package internal
 
 import (
+	"context"
+	"errors"
 	types "github.com/beatgammit/ginta/common"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type fetchFunc func(string) <-chan types.Resource
+type fetchFunc func(code string, report func(error)) <-chan types.LocatedResource
 
-type targetResource struct {
-	target string
-	types.Resource
+type bundleFetchFunc func(code, prefix string, report func(error)) <-chan types.LocatedResource
+
+// A registered source of resources for a language. The id identifies the provider it belongs to,
+// the language is the description the provider gave. Resources of sources with a higher priority
+// take precedence. Sources with a fetchBundle function are loaded by bundle, as lookups need them:
+// listBundles lists their bundles, which are kept sorted, so that every bundle follows its ancestors
+type source struct {
+	id          interface{}
+	priority    int
+	language    types.Language
+	fetch       fetchFunc
+	listBundles func(code string) []string
+	bundles     []string
+	fetchBundle bundleFetchFunc
+	// identifies the source in the store of the universe, never zero
+	serial uint32
 }
 
-type reply struct {
-	tr  string
-	err error
+// The part of a source fetched by a batch: all of its resources, or those of a single bundle
+type unit struct {
+	source *source
+	bundle string
 }
 
-type request struct {
-	key, code string
-	recurse   bool
-	reply     chan<- reply
+// A range of keys a lookup needs loaded: the keys of the bundle with the prefix, and with Deep,
+// all keys below the prefix as well
+type Scope struct {
+	Prefix string
+	Deep   bool
 }
 
-type bundleRequest struct {
-	recursive          bool
-	code, bundlePrefix string
-	reply              chan<- map[string]string
+// A fetch that has been started. Closing abandoned makes the fetch stop reading from its provider
+type fetchRun struct {
+	abandoned chan bool
+	batch     *batch
+	index     int
 }
 
-type languageRegister struct {
-	key, name string
-	fetch     fetchFunc
-	c         chan bool
+// A set of fetches whose resources are published together once the last fetch has finished. A
+// rebuild replaces the resources of the language, other batches are applied on top of them. Where
+// units define the same key, the unit of the source with the higher precedence wins
+type batch struct {
+	rebuild   bool
+	reason    types.ChangeReason
+	units     []unit
+	resources [][]types.LocatedResource
+	errors    [][]error
+	remaining int
+	runs      map[*fetchRun]bool
+	// closed once a rebuild has been published, or the language has been removed
+	rebuilt chan bool
 }
 
-type languageActivate struct {
-	code string
-	done chan bool
+// Recorded for fetches that were abandoned before their provider finished
+var ErrAbandoned = errors.New("fetch abandoned before the provider finished")
+
+type bundle map[string]entry
+
+// A resource value, its metadata and the place it is defined at: the index of its location in the
+// store of the universe, and its line. The location is zero for values set by Update, the metadata
+// nil if the source provides none
+type entry struct {
+	value    string
+	metadata *types.Metadata
+	location uint32
+	line     uint32
 }
 
-type bundle map[string]string
+// An immutable view of the resources of a language. Neither the map nor its
+// bundles may be modified once the snapshot has been published
+type snapshot struct {
+	entries map[string]bundle
+	// errors reported while loading the resources
+	errors []error
+	// resources defined by more than one source
+	conflicts []types.Conflict
+}
 
 type translation struct {
-	displayName    string
-	pendingFetches []fetchFunc
-	runningFetches int
-	blocked        []chan bool
-	entries        map[string]bundle
+	// holds the *common.Language describing the language, which is replaced when a provider of
+	// the language is registered or unregistered
+	description atomic.Value
+
+	// guarded by the lock of the universe. Sources are ordered by precedence, lowest first
+	sources []*source
+	// the same sources in order of registration, which merges their descriptions
+	registered []*source
+	// sources that have not been fetched yet, in the same order
+	pending []*source
+	// true once the language has been activated
+	active  bool
+	loading *batch
+	done    chan bool
+	// the resources of the sources, without overrides
+	base *snapshot
+	// the scopes requested from sources loaded by bundle, the bundles requested from each such
+	// source, and the bundles waiting for the running batch to finish. The channel of a bundle
+	// is closed once it has been published
+	scopes map[Scope]bool
+	loaded map[*source]map[string]chan bool
+	queued []unit
+	// incremented whenever a source loaded by bundle is added
+	generation int
+
+	// 1 if any source is loaded by bundle
+	lazy int32
+	// holds the map[Scope]bool of the scopes whose bundles have been published
+	ready atomic.Value
+
+	// 1 while fetches are pending or running
+	busy int32
+	// holds the current *snapshot
+	current atomic.Value
 }
 
-var (
-	requests         = make(chan request)
-	bundleRequests   = make(chan bundleRequest)
-	resourceEntry    = make(chan targetResource)
-	fetchFinished    = make(chan string)
-	registerLanguage = make(chan languageRegister)
-	list             = make(chan chan<- []*types.Language)
-	activateLanguage = make(chan languageActivate)
+// A subscriber to changes. Changes are queued, and passed to the handler in order by a goroutine
+// of the watcher, so that a slow handler never blocks the universe
+type watcher struct {
+	handler func(types.Change)
+	lock    sync.Mutex
+	queue   []types.Change
+	wake    chan bool
+	stop    chan bool
+}
 
-	universe = make(map[string]*translation)
+/*
+A set of languages. All methods are safe for concurrent use
+*/
+type Universe struct {
+	lock    sync.Mutex
+	closed  bool
+	handler func(error)
+	timeout time.Duration
 
-	pending = 0
-)
+	conflictHandler func(types.Conflict)
+	// overrides by language code, applied on top of the resources of the sources
+	overrides map[string]map[string]string
+	watchers  map[*watcher]bool
+
+	// holds the current map[string]*translation, which is replaced when a language is added
+	languages atomic.Value
+	// holds the current map[string]*counters, which is replaced when a language is first looked up
+	counters atomic.Value
+	// holds the current Recorder, which may be nil
+	recorder atomic.Value
+	// 1 in strict mode. Read without the lock, as every activation checks it
+	strict int32
+
+	store *store
+	// the serial of the source registered last
+	serials uint32
+}
+
+// Called for every resource requested, with the code of the language requested, the key requested, and
+// the code of the language and the key of the resource matching it, or empty strings for misses. Must be
+// safe for concurrent use
+type Recorder func(code, key, matchCode, match string)
+
+// lookup counters of a language, updated atomically
+type counters struct {
+	hits, misses, fallbacks uint64
+}
+
+var emptySnapshot = &snapshot{make(map[string]bundle), nil, nil}
+
+// Creates a new, empty universe
+func New() *Universe {
+	u := new(Universe)
+	u.languages.Store(make(map[string]*translation))
+	u.counters.Store(make(map[string]*counters))
+	u.recorder.Store(Recorder(nil))
+	u.store = newStore()
+	u.overrides = make(map[string]map[string]string)
+	u.watchers = make(map[*watcher]bool)
+
+	return u
+}
+
+// Closes the universe. Resources of fetches still running are discarded, and all watchers are stopped
+func (u *Universe) Close() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.closed = true
+	for w := range u.watchers {
+		delete(u.watchers, w)
+		close(w.stop)
+	}
+}
+
+func (u *Universe) language(code string) *translation {
+	return u.languages.Load().(map[string]*translation)[code]
+}
+
+func (t *translation) snapshot() *snapshot {
+	return t.current.Load().(*snapshot)
+}
 
-func init() {
-	go work()
+// Request a resource for a country code, either plain or recursively. If the
+// resource cannot be found for the code, the fallback codes are tried in order.
+// Fails with a *common.NotFoundError
+func (u *Universe) Request(code, key string, recurse bool, fallbacks ...string) (string, error) {
+	count, record := u.count(code), u.recorder.Load().(Recorder)
+	for i, next := range chain(code, fallbacks) {
+		if str, match, ok := u.lookup(next, key, recurse); ok {
+			atomic.AddUint64(&count.hits, 1)
+			if i > 0 {
+				atomic.AddUint64(&count.fallbacks, 1)
+			}
+			if record != nil {
+				record(code, key, next, match)
+			}
+			return str, nil
+		}
+	}
+
+	atomic.AddUint64(&count.misses, 1)
+	if record != nil {
+		record(code, key, "", "")
+	}
+	return key, &types.NotFoundError{key, code, u.Trace(code, key, recurse, fallbacks...).Candidates}
 }
 
-// Request a resource for a country code, either plain or recursively
-func Request(code, key string, recurse bool) (string, error) {
-	reply := make(chan reply)
-	defer close(reply)
+// Returns the lookup counters of a language, adding them on its first lookup. Languages without
+// sources are not counted, so that lookups of arbitrary codes cannot grow the counters: they get
+// counters that are discarded
+func (u *Universe) count(code string) *counters {
+	if c, ok := u.counters.Load().(map[string]*counters)[code]; ok {
+		return c
+	}
 
-	requests <- request{key, code, recurse, reply}
+	u.lock.Lock()
+	defer u.lock.Unlock()
 
-	replyVal := <-reply
-	return replyVal.tr, replyVal.err
+	current := u.counters.Load().(map[string]*counters)
+	if c, ok := current[code]; ok {
+		return c
+	}
+	if u.language(code) == nil {
+		return new(counters)
+	}
+
+	next := make(map[string]*counters, len(current)+1)
+	for k, v := range current {
+		next[k] = v
+	}
+	next[code] = new(counters)
+	u.counters.Store(next)
+
+	return next[code]
 }
 
-// Requests a bundle for a prefix, either plain or recursively
-func RequestBundle(code, base string, recursive bool) map[string]string {
-	reply := make(chan map[string]string)
-	defer close(reply)
+// Sets the function recording the resources requested. A nil recorder stops recording
+func (u *Universe) SetRecorder(record Recorder) {
+	u.recorder.Store(record)
+}
+
+// Returns the lookup statistics of all registered languages looked up so far, by code
+func (u *Universe) Stats() map[string]types.Stats {
+	result := make(map[string]types.Stats)
+	for code, c := range u.counters.Load().(map[string]*counters) {
+		result[code] = types.Stats{atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses), atomic.LoadUint64(&c.fallbacks)}
+	}
 
-	bundleRequests <- bundleRequest{
-		code:         code,
-		bundlePrefix: base,
-		recursive:    recursive,
-		reply:        reply,
+	return result
+}
+
+// Looks up a resource like Request, but records every candidate key checked, and where the match is defined
+func (u *Universe) Trace(code, key string, recurse bool, fallbacks ...string) types.Trace {
+	trace := types.Trace{Key: key, Code: code, Candidates: []types.Candidate{}}
+	for _, code := range chain(code, fallbacks) {
+		var entries map[string]bundle
+		if lang := u.language(code); lang != nil {
+			entries = lang.snapshot().entries
+		}
+
+		for hierarchy := types.HierarchicalKey(key); ; {
+			trace.Candidates = append(trace.Candidates, types.Candidate{code, hierarchy.String()})
+
+			prefix, local := hierarchy.Split()
+			if e, ok := entries[prefix][local]; ok {
+				trace.Found, trace.Value, trace.Metadata = true, e.value, e.metadata
+				// loaded after the snapshot, so that it holds the locations of its entries
+				trace.Origin, _ = e.origin(u.store.locations.Load().([]location))
+				return trace
+			}
+
+			if hierarchy = hierarchy.Parent(); !recurse || hierarchy.String() == "" {
+				break
+			}
+		}
+	}
+
+	return trace
+}
+
+// Requests a bundle for a prefix, either plain or recursively. Entries missing for
+// the code are filled from the fallback codes, earlier codes taking precedence
+func (u *Universe) RequestBundle(code, base string, recursive bool, fallbacks ...string) map[string]string {
+	result := make(map[string]string)
+	for _, code := range chain(code, fallbacks) {
+		if lang := u.language(code); lang != nil {
+			mergeBundles(result, lang.snapshot(), types.HierarchicalKey(base), recursive)
+		}
 	}
 
-	return <-reply
+	return result
 }
 
-// Registers a new language provider, using both a language and a resource enumerator function 
-func Register(lang <-chan types.Language, fetch func(string) <-chan types.Resource) {
+// Returns the values of all resources below a prefix (or all resources, for the empty prefix),
+// keyed by their full hierarchical key. Values of the code take precedence over those of the
+// fallback codes
+func (u *Universe) Subtree(code, prefix string, fallbacks ...string) map[string]string {
+	result := make(map[string]string)
+	for _, code := range chain(code, fallbacks) {
+		lang := u.language(code)
+		if lang == nil {
+			continue
+		}
+
+		for bundlePrefix, b := range lang.snapshot().entries {
+			if !within(bundlePrefix, prefix) {
+				continue
+			}
+
+			for key, e := range b {
+				full := fullKey(bundlePrefix, key)
+				if _, ok := result[full]; !ok {
+					result[full] = e.value
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// Returns the resources of a language below a prefix (or all, for the empty prefix), together with
+// their origin and metadata, sorted by key. Fallback codes are not consulted
+func (u *Universe) Resources(code, prefix string) []types.LocatedResource {
+	result := []types.LocatedResource{}
+	if lang := u.language(code); lang != nil {
+		entries := lang.snapshot().entries
+		locations := u.store.locations.Load().([]location)
+		for bundlePrefix, b := range entries {
+			if !within(bundlePrefix, prefix) {
+				continue
+			}
+
+			for key, e := range b {
+				origin, _ := e.origin(locations)
+				result = append(result, types.LocatedResource{types.Resource{fullKey(bundlePrefix, key), e.value}, origin, e.metadata})
+			}
+		}
+	}
+
+	sort.Sort(byKey(result))
+	return result
+}
+
+// reports whether the keys of a bundle are below a prefix
+func within(bundlePrefix, prefix string) bool {
+	return prefix == "" || bundlePrefix == prefix || strings.HasPrefix(bundlePrefix, prefix+types.ResourceKeySegmentSeparator)
+}
+
+type byKey []types.LocatedResource
+
+func (r byKey) Len() int           { return len(r) }
+func (r byKey) Less(i, j int) bool { return r[i].Key < r[j].Key }
+func (r byKey) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// Returns the full keys of all resources below a prefix, in the code and its fallback codes, sorted
+func (u *Universe) Keys(code, prefix string, fallbacks ...string) []string {
+	values := u.Subtree(code, prefix, fallbacks...)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func chain(code string, fallbacks []string) []string {
+	return append([]string{code}, fallbacks...)
+}
+
+// Registers a new language provider, using both a language and a resource enumerator function.
+// The enumerator function reports all failures to its callback before closing its channel. The id
+// identifies the provider for Unregister, and may be nil for providers that are never unregistered.
+// Resources of providers registered later take precedence
+func (u *Universe) Register(id interface{}, lang <-chan types.Language, fetch func(code string, report func(error)) <-chan types.LocatedResource) {
+	u.RegisterPriority(id, 0, lang, fetch)
+}
+
+// Like Register, but with an explicit priority. Resources of providers with a higher priority take
+// precedence, providers of equal priority are ordered by registration
+func (u *Universe) RegisterPriority(id interface{}, priority int, lang <-chan types.Language, fetch func(code string, report func(error)) <-chan types.LocatedResource) {
 	for l := range lang {
-		register := languageRegister{l.Code, l.DisplayName, fetchFunc(fetch), make(chan bool)}
-		defer close(register.c)
+		u.doRegister(l, &source{id, priority, l, fetchFunc(fetch), nil, nil, nil, 0})
+	}
+}
 
-		registerLanguage <- register
-		<-register.c
+// Like RegisterPriority, but for a provider whose resources are loaded by bundle, once lookups need
+// them (See ActivateContext). The bundles function lists the prefixes of the bundles of a language,
+// and is called again by Reload. The fetch function fetches the resources of a single bundle. All
+// resources of a bundle must be within its prefix, the empty prefix standing for all keys. Where
+// bundles of the provider define the same key, the bundle with the longer prefix wins, whichever
+// is loaded first
+func (u *Universe) RegisterBundles(id interface{}, priority int, lang <-chan types.Language, bundles func(code string) []string, fetch func(code, prefix string, report func(error)) <-chan types.LocatedResource) {
+	for l := range lang {
+		u.doRegister(l, &source{id, priority, l, nil, bundles, sorted(bundles(l.Code)), bundleFetchFunc(fetch), 0})
 	}
 }
 
-// Changes a mapped resource value
-func Update(code, key, val string) {
-	resourceEntry <- targetResource{code, types.Resource{key, val}}
+// returns a sorted copy of bundle prefixes. Every prefix sorts after the prefixes of its ancestors
+func sorted(bundles []string) []string {
+	result := append([]string{}, bundles...)
+	sort.Strings(result)
+
+	return result
 }
 
-// Lists all available languages
-func List() []*types.Language {
-	result := make(chan []*types.Language)
-	defer close(result)
+// Removes all sources registered with the id. Active languages are rebuilt from their remaining
+// sources, and described by them, languages without sources are removed. Returns once all rebuilds
+// have been published, and the store no longer holds the locations of the sources, nor keys no
+// language defines any longer
+func (u *Universe) Unregister(id interface{}) {
+	if id == nil {
+		return
+	}
+
+	u.lock.Lock()
+	languages := u.languages.Load().(map[string]*translation)
+	remaining := make(map[string]*translation, len(languages))
+	waiting := []chan bool{}
+	removed := make(map[uint32]bool)
+	for code, lang := range languages {
+		sources := without(lang.sources, id)
+		if len(sources) == len(lang.sources) {
+			remaining[code] = lang
+			continue
+		}
+
+		for _, s := range lang.sources {
+			if s.id == id {
+				removed[s.serial] = true
+			}
+		}
+
+		lang.sources = sources
+		lang.pending = without(lang.pending, id)
+		lang.forget(id)
+		if len(sources) == 0 {
+			u.remove(code, lang)
+			continue
+		}
+
+		lang.registered = without(lang.registered, id)
+		lang.description.Store(describe(lang.registered))
 
-	list <- result
+		remaining[code] = lang
+		if lang.active {
+			waiting = append(waiting, u.rebuild(code, lang, types.ChangeUnregistered))
+		} else {
+			lang.finishIfIdle()
+		}
+	}
+	u.languages.Store(remaining)
+	u.lock.Unlock()
+
+	for _, rebuilt := range waiting {
+		<-rebuilt
+	}
 
-	return <-result
+	if len(removed) > 0 {
+		u.lock.Lock()
+		u.store.compact(u.snapshots(), removed)
+		u.lock.Unlock()
+	}
 }
 
-// makes a language ready for use by loading all associated resources
-func Activate(code string) bool {
-	c := make(chan bool)
-	defer close(c)
-	activateLanguage <- languageActivate{code, c}
+// returns the snapshots of all languages, with and without overrides. Must be called with the lock
+// of the universe held
+func (u *Universe) snapshots() []*snapshot {
+	result := []*snapshot{}
+	for _, lang := range u.languages.Load().(map[string]*translation) {
+		result = append(result, lang.base, lang.snapshot())
+	}
 
-	return <-c
+	return result
 }
 
-func work() {
+// Fetches all resources of an active language again, and replaces its resources once all sources
+// have delivered. Lookups see the previous resources until then. Running fetches of the language
+// are abandoned. Sources loaded by bundle list their bundles again, and bundles that are gone are
+// dropped. Returns the errors reported while reloading, and nothing for inactive languages
+func (u *Universe) Reload(code string) error {
+	u.lock.Lock()
+	lang := u.language(code)
+	if lang == nil || !lang.active {
+		u.lock.Unlock()
+		return nil
+	}
+
+	lazy := []*source{}
+	for _, s := range lang.sources {
+		if s.listBundles != nil {
+			lazy = append(lazy, s)
+		}
+	}
+	u.lock.Unlock()
+
+	// listed without the lock, as providers may take their time
+	listed := make([][]string, len(lazy))
+	for i, s := range lazy {
+		listed[i] = sorted(s.listBundles(code))
+	}
+
+	u.lock.Lock()
+	if u.language(code) != lang {
+		u.lock.Unlock()
+		return nil
+	}
+
+	for i, s := range lazy {
+		lang.relist(s, listed[i])
+	}
+	rebuilt := u.rebuild(code, lang, types.ChangeReloaded)
+	u.lock.Unlock()
+
+	<-rebuilt
+
+	if errs := lang.snapshot().errors; len(errs) > 0 {
+		return types.LoadErrors(append([]error{}, errs...))
+	}
+
+	return nil
+}
+
+// replaces the bundles of a source loaded by bundle, releasing the callers waiting for bundles that
+// are gone. Must be called with the lock of the universe held
+func (t *translation) relist(s *source, bundles []string) {
+	listed := make(map[string]bool, len(bundles))
+	for _, bundle := range bundles {
+		listed[bundle] = true
+	}
+
+	for bundle, done := range t.loaded[s] {
+		if !listed[bundle] {
+			release(done)
+			delete(t.loaded[s], bundle)
+		}
+	}
+
+	s.bundles = bundles
+}
+
+// releases the callers waiting for the bundles of the sources with the id, and drops the bundles.
+// Must be called with the lock of the universe held
+func (t *translation) forget(id interface{}) {
+	for s, bundles := range t.loaded {
+		if s.id == id {
+			for _, done := range bundles {
+				release(done)
+			}
+			delete(t.loaded, s)
+		}
+	}
+
+	queued := []unit{}
+	for _, next := range t.queued {
+		if next.source.id != id {
+			queued = append(queued, next)
+		}
+	}
+	t.queued = queued
+}
+
+// closes a channel unless it is closed already. Must be called with the lock of the universe held
+func release(done chan bool) {
+	select {
+	case <-done:
+	default:
+		close(done)
+	}
+}
+
+func without(sources []*source, id interface{}) []*source {
+	result := []*source{}
+	for _, s := range sources {
+		if s.id != id {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+// Sets the function that is called for every error reported while loading resources. The
+// function may be called from any goroutine
+func (u *Universe) SetErrorHandler(handler func(error)) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.handler = handler
+}
+
+// Passes an error to the error handler, if one is set
+func (u *Universe) Report(err error) {
+	u.lock.Lock()
+	handler := u.handler
+	u.lock.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// In strict mode, activating a language fails if errors were reported while loading it
+func (u *Universe) SetStrict(strict bool) {
+	if strict {
+		atomic.StoreInt32(&u.strict, 1)
+	} else {
+		atomic.StoreInt32(&u.strict, 0)
+	}
+}
+
+// Sets the function that is called for every resource found to be defined by more than one source,
+// once the resources are loaded. The function is called from a separate goroutine
+func (u *Universe) SetConflictHandler(handler func(types.Conflict)) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.conflictHandler = handler
+}
+
+// Calls the handler for every change of the resources of any language, until the returned function
+// is called. The handler is called from a separate goroutine, one change at a time and in order
+func (u *Universe) Watch(handler func(types.Change)) func() {
+	w := &watcher{handler: handler, wake: make(chan bool, 1), stop: make(chan bool)}
+	go w.run()
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.closed {
+		close(w.stop)
+	} else {
+		u.watchers[w] = true
+	}
+
+	return func() {
+		u.lock.Lock()
+		defer u.lock.Unlock()
+
+		if u.watchers[w] {
+			delete(u.watchers, w)
+			close(w.stop)
+		}
+	}
+}
+
+func (w *watcher) enqueue(change types.Change) {
+	w.lock.Lock()
+	w.queue = append(w.queue, change)
+	w.lock.Unlock()
+
+	select {
+	case w.wake <- true:
+	default:
+	}
+}
+
+func (w *watcher) run() {
 	for {
 		select {
-		case l := <-registerLanguage:
-			doRegister(&l)
-		case activate := <-activateLanguage:
-			doActivate(&activate)
-		case finished := <-fetchFinished:
-			doFetchFinished(finished)
-		case entry := <-resourceEntry:
-			doAddEntry(&entry)
-		case request := <-bundleRequests:
-			result := make(map[string]string)
-			mergeBundles(result, types.HierarchicalKey(request.bundlePrefix), &request)
+		case <-w.wake:
+		case <-w.stop:
+			return
+		}
 
-			request.reply <- result
-		case request := <-requests:
-			doFetchResource(&request)
-		case reply := <-list:
-			doListLanguages(reply)
+		w.lock.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.lock.Unlock()
+
+		for _, change := range queue {
+			select {
+			case <-w.stop:
+				return
+			default:
+				w.handler(change)
+			}
 		}
 	}
 }
 
-func mergeBundles(result map[string]string, k types.HierarchicalKey, request *bundleRequest) {
-	if entries, ok := universe[request.code].entries[k.String()]; ok {
-		for key, val := range entries {
+// Returns the resources of a language defined by more than one source. Conflicts are found when
+// the resources are loaded, and a reload finds them anew
+func (u *Universe) Conflicts(code string) []types.Conflict {
+	if lang := u.language(code); lang != nil {
+		return append([]types.Conflict{}, lang.snapshot().conflicts...)
+	}
 
-			if _, ok := result[key]; !ok {
-				result[key] = val
+	return nil
+}
+
+// Returns the errors reported while loading a language
+func (u *Universe) Errors(code string) []error {
+	if lang := u.language(code); lang != nil {
+		return append([]error{}, lang.snapshot().errors...)
+	}
+
+	return nil
+}
+
+// Overrides a resource value. See ApplyOverrides
+func (u *Universe) Update(code, key, val string) {
+	u.ApplyOverrides(code, map[string]string{key: val})
+}
+
+// Sets several overrides of a language at once, and removes the overrides of the deleted keys first.
+// Overrides may set resources to the empty string. Overrides take precedence over the resources of
+// all sources, and are kept when the language is reloaded. Overrides of languages without sources
+// are kept until a source for the language is registered
+func (u *Universe) ApplyOverrides(code string, set map[string]string, deleted ...string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	overrides := make(map[string]string, len(u.overrides[code])+len(set))
+	for key, val := range u.overrides[code] {
+		overrides[key] = val
+	}
+
+	for _, key := range deleted {
+		delete(overrides, key)
+	}
+	for key, val := range set {
+		overrides[key] = val
+	}
+
+	u.setOverrides(code, overrides)
+}
+
+// Removes all overrides of a language
+func (u *Universe) ClearOverrides(code string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.setOverrides(code, nil)
+}
+
+// Returns the overrides of a language
+func (u *Universe) Overrides(code string) map[string]string {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	result := make(map[string]string, len(u.overrides[code]))
+	for key, val := range u.overrides[code] {
+		result[key] = val
+	}
+
+	return result
+}
+
+// replaces the overrides of a language, and publishes them. Must be called with the lock of the universe held
+func (u *Universe) setOverrides(code string, overrides map[string]string) {
+	if len(overrides) == 0 {
+		delete(u.overrides, code)
+	} else {
+		u.overrides[code] = overrides
+	}
+
+	if lang := u.language(code); lang != nil {
+		u.publish(code, lang, types.ChangeOverridden)
+	}
+}
+
+// Lists all available languages
+func (u *Universe) List() []*types.Language {
+	languages := u.languages.Load().(map[string]*translation)
+	result := make([]*types.Language, 0, len(languages))
+	for _, val := range languages {
+		result = append(result, val.info())
+	}
+
+	return result
+}
+
+// makes a language ready for use by loading all associated resources. Returns false for unknown
+// languages. In strict mode, returns the errors reported while loading the language
+func (u *Universe) Activate(code string) (bool, error) {
+	return u.ActivateContext(context.Background(), code)
+}
+
+// Like Activate, but stops waiting for running fetches once the context is done, and returns
+// the error of the context. The fetches keep running for other callers. Sources loaded by bundle
+// (See RegisterBundles) only load the bundles the scopes need, and keep them loaded
+func (u *Universe) ActivateContext(ctx context.Context, code string, scopes ...Scope) (bool, error) {
+	lang := u.language(code)
+	if lang == nil {
+		return false, nil
+	}
+
+	if atomic.LoadInt32(&lang.busy) != 0 {
+		u.lock.Lock()
+		lang.active = true
+		done := lang.done
+		if lang.loading == nil {
+			u.startPending(code, lang)
+		}
+		u.lock.Unlock()
+
+		if done != nil {
+			select {
+			case <-done:
+			case <-ctx.Done():
+				return true, ctx.Err()
+			}
+		}
+	}
+
+	if len(scopes) > 0 && atomic.LoadInt32(&lang.lazy) != 0 {
+		if err := u.load(ctx, code, lang, scopes); err != nil {
+			return true, err
+		}
+	}
+
+	return true, u.failure(lang)
+}
+
+// loads the bundles the scopes need from the sources loaded by bundle, and waits until they have
+// been published. Scopes loaded before are skipped without taking the lock of the universe
+func (u *Universe) load(ctx context.Context, code string, lang *translation, scopes []Scope) error {
+	ready := lang.ready.Load().(map[Scope]bool)
+	missing := []Scope{}
+	for _, scope := range scopes {
+		if !ready[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	u.lock.Lock()
+	generation := lang.generation
+	lang.active = true
+	units, waiting := []unit{}, []chan bool{}
+	for _, scope := range missing {
+		lang.scopes[scope] = true
+		for _, s := range lang.sources {
+			if s.fetchBundle == nil || lang.isPending(s) {
+				continue
+			}
+
+			units = append(units, lang.require(s)...)
+			for _, bundle := range s.bundles {
+				if scope.needs(bundle) {
+					waiting = append(waiting, lang.loaded[s][bundle])
+				}
 			}
 		}
 	}
 
-	if request.recursive && k.String() != "" {
-		mergeBundles(result, k.Parent(), request)
+	if len(units) > 0 {
+		lang.queued = append(lang.queued, units...)
+		if lang.loading == nil {
+			u.startPending(code, lang)
+		}
 	}
+	u.lock.Unlock()
+
+	for _, done := range waiting {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	// sources added in the meantime have not loaded the scopes yet
+	if lang.generation == generation {
+		current := lang.ready.Load().(map[Scope]bool)
+		next := make(map[Scope]bool, len(current)+len(missing))
+		for scope := range current {
+			next[scope] = true
+		}
+		for _, scope := range missing {
+			next[scope] = true
+		}
+		lang.ready.Store(next)
+	}
+
+	return nil
 }
 
-func doActivate(activate *languageActivate) {
-	if entry, ok := universe[activate.code]; ok {
-		if length := len(entry.pendingFetches); length > 0 {
-			entry.runningFetches += length
-			entry.blocked = append(entry.blocked, activate.done)
-			list := entry.pendingFetches
-			entry.pendingFetches = []fetchFunc{}
-			for _, fun := range list {
-				code := activate.code
-				fun := fun
-				go func() {
-					for next := range fun(activate.code) {
+// reports whether the keys of the scope may be defined in the bundle with the prefix
+func (s Scope) needs(bundle string) bool {
+	return bundle == "" || s.Prefix == bundle || strings.HasPrefix(s.Prefix, bundle+types.ResourceKeySegmentSeparator) || (s.Deep && within(bundle, s.Prefix))
+}
+
+// returns the units for the bundles of a source loaded by bundle that the scopes requested so far need,
+// and that have not been requested before. Must be called with the lock of the universe held
+func (t *translation) require(s *source) []unit {
+	if t.loaded[s] == nil {
+		t.loaded[s] = make(map[string]chan bool)
+	}
 
-						resourceEntry <- targetResource{code, next}
-					}
+	units := []unit{}
+	for _, bundle := range s.bundles {
+		if _, ok := t.loaded[s][bundle]; ok {
+			continue
+		}
 
-					fetchFinished <- code
-				}()
+		for scope := range t.scopes {
+			if scope.needs(bundle) {
+				t.loaded[s][bundle] = make(chan bool)
+				units = append(units, unit{s, bundle})
+				break
 			}
+		}
+	}
+
+	return units
+}
+
+// reports whether a source has not been fetched yet. Must be called with the lock of the universe held
+func (t *translation) isPending(s *source) bool {
+	for _, p := range t.pending {
+		if p == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Abandons all running fetches of a language. Callers waiting for the activation of the language
+// resume, and resources delivered by the abandoned fetches later on are discarded. An ErrAbandoned
+// is recorded for each abandoned fetch
+func (u *Universe) Abandon(code string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if lang := u.language(code); lang != nil && lang.loading != nil {
+		for f := range lang.loading.runs {
+			u.abandon(code, lang, f)
+		}
+	}
+}
+
+// Sets the time after which running fetches are abandoned automatically. Zero disables the timeout,
+// and applies to fetches started afterwards
+func (u *Universe) SetFetchTimeout(timeout time.Duration) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.timeout = timeout
+}
+
+func (u *Universe) failure(lang *translation) error {
+	if errs := lang.snapshot().errors; atomic.LoadInt32(&u.strict) != 0 && len(errs) > 0 {
+		return types.LoadErrors(append([]error{}, errs...))
+	}
+
+	return nil
+}
+
+// starts a batch for the sources that have not been fetched yet. Must be called with the lock of
+// the universe held, while no batch is running. The resources of a batch are applied on top of those
+// loaded before, so if a loaded source takes precedence over a pending one, the language is rebuilt
+func (u *Universe) startPending(code string, lang *translation) {
+	if len(lang.pending) == 0 && len(lang.queued) == 0 {
+		return
+	}
+
+	if len(lang.pending) > 0 && lang.overtakes(lang.pending[0]) {
+		u.rebuild(code, lang, types.ChangeLoaded)
+		return
+	}
+
+	units := lang.queued
+	for _, s := range lang.pending {
+		if s.fetchBundle == nil {
+			units = append(units, unit{s, ""})
 		} else {
-			activate.done <- true
+			units = append(units, lang.require(s)...)
 		}
-	} else {
-		activate.done <- false
 	}
+
+	lang.pending, lang.queued = []*source{}, nil
+	u.start(code, lang, &batch{units: units, reason: types.ChangeLoaded})
+}
+
+// reports whether a loaded source takes precedence over the first pending one. Must be called with
+// the lock of the universe held
+func (t *translation) overtakes(first *source) bool {
+	pending := make(map[*source]bool)
+	for _, s := range t.pending {
+		pending[s] = true
+	}
+
+	after := false
+	for _, s := range t.sources {
+		after = after || s == first
+		if after && !pending[s] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replaces a running batch by a rebuild from all sources of the language, and returns a channel
+// that is closed once the rebuild has been published. Must be called with the lock of the universe held
+func (u *Universe) rebuild(code string, lang *translation, reason types.ChangeReason) chan bool {
+	units := []unit{}
+	for _, s := range lang.sources {
+		if s.fetchBundle == nil {
+			units = append(units, unit{s, ""})
+			continue
+		}
+
+		lang.require(s)
+		bundles := []string{}
+		for bundle := range lang.loaded[s] {
+			bundles = append(bundles, bundle)
+		}
+		sort.Strings(bundles)
+		for _, bundle := range bundles {
+			units = append(units, unit{s, bundle})
+		}
+	}
+
+	b := &batch{rebuild: true, reason: reason, units: units}
+	if running := lang.loading; running != nil {
+		u.cancel(running)
+		if running.rebuild {
+			b.rebuilt = running.rebuilt
+		}
+	}
+	if b.rebuilt == nil {
+		b.rebuilt = make(chan bool)
+	}
+
+	lang.pending, lang.queued = []*source{}, nil
+	u.start(code, lang, b)
+
+	return b.rebuilt
+}
+
+// starts the fetches of a batch. Must be called with the lock of the universe held
+func (u *Universe) start(code string, lang *translation, b *batch) {
+	b.resources = make([][]types.LocatedResource, len(b.units))
+	b.errors = make([][]error, len(b.units))
+	b.remaining = len(b.units)
+	b.runs = make(map[*fetchRun]bool)
+	lang.loading = b
+
+	if b.remaining == 0 {
+		u.complete(code, lang, b)
+		return
+	}
+
+	for i, next := range b.units {
+		f := &fetchRun{make(chan bool), b, i}
+		b.runs[f] = true
+		go u.fetch(code, lang, next.fetch(), f, u.timeout)
+	}
+}
+
+// returns the function fetching the resources of the unit
+func (n unit) fetch() fetchFunc {
+	if n.source.fetchBundle == nil {
+		return n.source.fetch
+	}
+
+	return func(code string, report func(error)) <-chan types.LocatedResource {
+		return n.source.fetchBundle(code, n.bundle, report)
+	}
+}
+
+func (u *Universe) fetch(code string, lang *translation, fun fetchFunc, f *fetchRun, timeout time.Duration) {
+	var errLock sync.Mutex
+	errs := []error{}
+	report := func(err error) {
+		errLock.Lock()
+		errs = append(errs, err)
+		errLock.Unlock()
+
+		u.Report(err)
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	resources := []types.LocatedResource{}
+	c := fun(code, report)
+	for open := true; open; {
+		select {
+		case next, ok := <-c:
+			if open = ok; ok {
+				resources = append(resources, next)
+			}
+		case <-expired:
+			u.lock.Lock()
+			u.abandon(code, lang, f)
+			u.lock.Unlock()
+			go drain(c)
+			return
+		case <-f.abandoned:
+			go drain(c)
+			return
+		}
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	b := f.batch
+	if !b.runs[f] {
+		return
+	}
+	delete(b.runs, f)
+
+	errLock.Lock()
+	b.resources[f.index] = resources
+	b.errors[f.index] = errs
+	errLock.Unlock()
+
+	b.remaining--
+	u.complete(code, lang, b)
+}
+
+// abandons a running fetch. Must be called with the lock of the universe held
+func (u *Universe) abandon(code string, lang *translation, f *fetchRun) {
+	b := f.batch
+	if !b.runs[f] {
+		return
+	}
+
+	delete(b.runs, f)
+	close(f.abandoned)
+
+	err := &types.LoadError{Code: code, Err: ErrAbandoned}
+	b.errors[f.index] = []error{err}
+	go u.Report(err)
+
+	b.remaining--
+	u.complete(code, lang, b)
+}
+
+// stops all fetches of a batch without recording errors. Must be called with the lock of the universe held
+func (u *Universe) cancel(b *batch) {
+	for f := range b.runs {
+		delete(b.runs, f)
+		close(f.abandoned)
+	}
+}
+
+// removes a language whose last source has been unregistered, releasing all waiting callers. Must
+// be called with the lock of the universe held
+func (u *Universe) remove(code string, lang *translation) {
+	if b := lang.loading; b != nil {
+		u.cancel(b)
+		if b.rebuilt != nil {
+			close(b.rebuilt)
+		}
+		lang.loading = nil
+	}
+
+	lang.finishIfIdle()
+	u.notify(code, lang.snapshot(), emptySnapshot, types.ChangeUnregistered)
 }
 
-func doRegister(l *languageRegister) {
-	entry, ok := universe[l.key]
-	if !ok {
+// publishes the resources of a batch once its last fetch has finished, and starts the sources
+// registered in the meantime if the language is active. Must be called with the lock of the
+// universe held
+func (u *Universe) complete(code string, lang *translation, b *batch) {
+	if b.remaining > 0 || lang.loading != b {
+		return
+	}
+	lang.loading = nil
+
+	if !u.closed {
+		layers := make([]layer, len(b.units))
+		errs := []error{}
+		for i, next := range b.units {
+			layers[i] = layer{next.source, b.resources[i]}
+			errs = append(errs, b.errors[i]...)
+		}
+
+		base := lang.base
+		if b.rebuild {
+			base = emptySnapshot
+		}
+
+		rank := make(map[uint32]int, len(lang.sources))
+		for i, s := range lang.sources {
+			rank[s.serial] = i
+		}
+
+		previous, next := lang.base, base.apply(code, layers, errs, rank, u.store)
+		lang.base = next
+		u.publish(code, lang, b.reason)
+
+		// keys a rebuild dropped may have been the last users of their interned strings
+		if b.rebuild && previous.drops(next) {
+			u.store.compact(u.snapshots(), nil)
+		}
+
+		if found := next.conflicts[len(base.conflicts):]; len(found) > 0 && u.conflictHandler != nil {
+			go func(handler func(types.Conflict)) {
+				for _, c := range found {
+					handler(c)
+				}
+			}(u.conflictHandler)
+		}
+	}
+
+	if b.rebuilt != nil {
+		close(b.rebuilt)
+	}
+
+	for _, next := range b.units {
+		if done, ok := lang.loaded[next.source][next.bundle]; ok {
+			release(done)
+		}
+	}
+
+	if lang.active {
+		u.startPending(code, lang)
+	}
+	lang.finishIfIdle()
+}
+
+// releases the callers waiting for activation, once no fetches are pending or running. Must be
+// called with the lock of the universe held
+func (t *translation) finishIfIdle() {
+	if t.loading == nil && len(t.pending) == 0 && t.done != nil {
+		close(t.done)
+		t.done = nil
+		atomic.StoreInt32(&t.busy, 0)
+	}
+}
+
+// consumes the remaining resources of an abandoned fetch, so that its provider is not blocked forever
+func drain(c <-chan types.LocatedResource) {
+	for _ = range c {
+	}
+}
+
+// Returns a copy of the description of a language
+func (u *Universe) Language(code string) (*types.Language, bool) {
+	if lang := u.language(code); lang != nil {
+		return lang.info(), true
+	}
+
+	return nil, false
+}
+
+func (t *translation) info() *types.Language {
+	description := *t.description.Load().(*types.Language)
+	return &description
+}
+
+func (u *Universe) doRegister(l types.Language, s *source) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.serials++
+	s.serial = u.serials
+
+	code := l.Code
+	languages := u.languages.Load().(map[string]*translation)
+	entry, ok := languages[code]
+	if ok {
+		entry.registered = append(entry.registered, s)
+		entry.description.Store(describe(entry.registered))
+	} else {
 		entry = &translation{
-			displayName:    l.name,
-			pendingFetches: []fetchFunc{},
-			blocked:        []chan bool{},
-			entries:        make(map[string]bundle),
+			sources:    []*source{},
+			registered: []*source{s},
+			pending:    []*source{},
+			scopes:     make(map[Scope]bool),
+			loaded:     make(map[*source]map[string]chan bool),
+		}
+		entry.description.Store(describe(entry.registered))
+		entry.ready.Store(make(map[Scope]bool))
+		entry.base = emptySnapshot
+		entry.current.Store(emptySnapshot)
+		u.publish(code, entry, types.ChangeOverridden)
+
+		copied := make(map[string]*translation, len(languages)+1)
+		for key, val := range languages {
+			copied[key] = val
+		}
+		copied[code] = entry
+		u.languages.Store(copied)
+	}
+
+	if s.fetchBundle != nil {
+		atomic.StoreInt32(&entry.lazy, 1)
+		entry.generation++
+		entry.ready.Store(make(map[Scope]bool))
+	}
+
+	entry.sources = insert(entry.sources, s)
+	entry.pending = insert(entry.pending, s)
+	if entry.done == nil {
+		entry.done = make(chan bool)
+	}
+	atomic.StoreInt32(&entry.busy, 1)
+}
+
+// merges the descriptions of sources: earlier sources take precedence, later ones fill in what they left out
+func describe(sources []*source) *types.Language {
+	description := sources[0].language
+	for _, s := range sources[1:] {
+		description.Merge(s.language)
+	}
+
+	return &description
+}
+
+// inserts a source after all sources of lower or equal priority
+func insert(sources []*source, s *source) []*source {
+	i := len(sources)
+	for i > 0 && sources[i-1].priority > s.priority {
+		i--
+	}
+
+	return append(sources[:i:i], append([]*source{s}, sources[i:]...)...)
+}
+
+// publishes the resources of the sources of a language, with its overrides applied on top, and
+// notifies the watchers. Must be called with the lock of the universe held
+func (u *Universe) publish(code string, lang *translation, reason types.ChangeReason) {
+	current := lang.base
+	if overrides := u.overrides[code]; len(overrides) > 0 {
+		resources := make([]types.LocatedResource, 0, len(overrides))
+		for key, val := range overrides {
+			resources = append(resources, types.LocatedResource{types.Resource{key, val}, types.Origin{}, nil})
+		}
+		current = current.apply(code, []layer{{nil, resources}}, nil, nil, u.store)
+	}
+
+	previous := lang.snapshot()
+	lang.current.Store(current)
+	u.notify(code, previous, current, reason)
+}
+
+// notifies all watchers of the resources that differ between two snapshots of a language, if any.
+// Must be called with the lock of the universe held
+func (u *Universe) notify(code string, previous, current *snapshot, reason types.ChangeReason) {
+	if len(u.watchers) == 0 {
+		return
+	}
+
+	if keys, prefixes := differences(previous, current); len(keys) > 0 {
+		change := types.Change{code, reason, keys, prefixes}
+		for w := range u.watchers {
+			w.enqueue(change)
 		}
-		universe[l.key] = entry
 	}
+}
 
-	entry.pendingFetches = append(entry.pendingFetches, l.fetch)
+// returns the full keys of the resources that differ between two snapshots, and the prefixes of
+// their bundles, both sorted. Bundles shared by the snapshots are skipped
+func differences(previous, current *snapshot) ([]string, []string) {
+	keys, prefixes := []string{}, []string{}
+	compare := func(prefix string, from, to bundle) {
+		if reflect.ValueOf(from).Pointer() == reflect.ValueOf(to).Pointer() {
+			return
+		}
 
-	l.c <- !ok
+		changed := false
+		for key, e := range to {
+			if old, ok := from[key]; !ok || old.value != e.value {
+				keys, changed = append(keys, fullKey(prefix, key)), true
+			}
+		}
+		for key := range from {
+			if _, ok := to[key]; !ok {
+				keys, changed = append(keys, fullKey(prefix, key)), true
+			}
+		}
+
+		if changed {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	for prefix, b := range current.entries {
+		compare(prefix, previous.entries[prefix], b)
+	}
+	for prefix, b := range previous.entries {
+		if _, ok := current.entries[prefix]; !ok {
+			compare(prefix, b, nil)
+		}
+	}
+
+	sort.Strings(keys)
+	sort.Strings(prefixes)
+	return keys, prefixes
+}
+
+// joins a bundle prefix and a key within the bundle
+func fullKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + types.ResourceKeySegmentSeparator + key
+}
+
+// The resources of a single source, or the overrides if the source is nil
+type layer struct {
+	source    *source
+	resources []types.LocatedResource
 }
 
-func doFetchFinished(finished string) {
-	entry := universe[finished]
-	entry.runningFetches--
+// returns the serial of the source of the layer, zero for overrides
+func (l layer) serial() uint32 {
+	if l.source == nil {
+		return 0
+	}
+
+	return l.source.serial
+}
 
-	if entry.runningFetches == 0 {
-		for _, c := range entry.blocked {
-			c <- true
+// returns the interned copy of a prefix or key of a source. Overrides are not interned, as they
+// come and go at runtime
+func (l layer) intern(st *store, str string) string {
+	if l.source == nil {
+		return str
+	}
+
+	return st.intern(str)
+}
+
+// returns a copy of the snapshot with the layers applied in order, and the errors added. Only the
+// bundles touched by the resources are copied, all others are shared with the original snapshot.
+// With a rank of the serials of sources, the sources ranked lower keep none of their values where a
+// source ranked higher defines them, regardless of the order of application. Resources replacing or
+// shadowed by a value of another source are recorded as conflicts. Keys of sources are interned, and
+// locations kept in the store
+func (s *snapshot) apply(code string, layers []layer, errs []error, rank map[uint32]int, st *store) *snapshot {
+	entries := make(map[string]bundle, len(s.entries))
+	for prefix, b := range s.entries {
+		entries[prefix] = b
+	}
+
+	conflicts := s.conflicts[:len(s.conflicts):len(s.conflicts)]
+	copied := make(map[string]bool)
+	for _, l := range layers {
+		for _, res := range l.resources {
+			prefix, key := types.HierarchicalKey(res.Key).Split()
+			previous, defined := entries[prefix][key]
+			owner := st.list[previous.location].owner
+			shadowed := defined && rank != nil && owner != 0 && rank[owner] > rank[l.serial()]
+
+			if previousOrigin, located := previous.origin(st.list); defined && located && res.Origin != (types.Origin{}) {
+				if shadowed {
+					conflicts = append(conflicts, types.Conflict{code, res.Key, previousOrigin, res.Origin})
+				} else {
+					conflicts = append(conflicts, types.Conflict{code, res.Key, res.Origin, previousOrigin})
+				}
+			}
+
+			if shadowed {
+				continue
+			}
+
+			if !copied[prefix] {
+				copied[prefix] = true
+				b := make(bundle, len(entries[prefix])+1)
+				for k, v := range entries[prefix] {
+					b[k] = v
+				}
+				entries[l.intern(st, prefix)] = b
+			}
+
+			line := uint32(0)
+			if res.Origin.Line > 0 {
+				line = uint32(res.Origin.Line)
+			}
+			entries[prefix][l.intern(st, key)] = entry{res.Value, res.Metadata, st.locate(l.serial(), res.Origin), line}
 		}
+	}
 
-		entry.blocked = make([]chan bool, 0)
+	allErrors := s.errors
+	if len(errs) > 0 {
+		allErrors = append(append([]error{}, allErrors...), errs...)
 	}
+
+	return &snapshot{entries, allErrors, conflicts}
 }
 
-func doAddEntry(entry *targetResource) {
-	if ptr := universe[entry.target]; ptr != nil {
-		prefix, key := types.HierarchicalKey(entry.Key).Split()
-		m, ok := ptr.entries[prefix]
-		if !ok {
-			m = make(bundle)
-			ptr.entries[prefix] = m
+func mergeBundles(result map[string]string, s *snapshot, k types.HierarchicalKey, recursive bool) {
+	if entries, ok := s.entries[k.String()]; ok {
+		for key, val := range entries {
+
+			if _, ok := result[key]; !ok {
+				result[key] = val.value
+			}
 		}
-		m[key] = entry.Value
+	}
+
+	if recursive && k.String() != "" {
+		mergeBundles(result, s, k.Parent(), recursive)
 	}
 }
 
-func doFetchResource(request *request) {
-	if lang, ok := universe[request.code]; ok {
+// performs the hierarchical walk for a key within a single language
+func (u *Universe) lookup(code, key string, recurse bool) (string, string, bool) {
+	if lang := u.language(code); lang != nil {
+		entries := lang.snapshot().entries
 
 		iteration := true
 
-		hierarchy := types.HierarchicalKey(request.key)
+		hierarchy := types.HierarchicalKey(key)
 
 		for iteration {
 			prefix, key := hierarchy.Split()
 
-			if m, ok := lang.entries[prefix]; ok {
-				if str, ok := m[key]; ok {
-					request.reply <- reply{str, nil}
-					return
+			if m, ok := entries[prefix]; ok {
+				if e, ok := m[key]; ok {
+					return e.value, hierarchy.String(), true
 				}
 			}
 
 			hierarchy = hierarchy.Parent()
-			iteration = request.recurse && hierarchy.String() != ""
+			iteration = recurse && hierarchy.String() != ""
 		}
 	}
 
-	request.reply <- reply{request.key, types.ResourceNotFoundError(request.key)}
-}
-
-func doListLanguages(reply chan<- []*types.Language) {
-	result := make([]*types.Language, len(universe))
-	i := 0
-	for key, val := range universe {
-		result[i] = &types.Language{key, val.displayName}
-		i++
-	}
-
-	reply <- result
+	return "", "", false
 }
@@ -1,7 +1,11 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"github.com/beatgammit/ginta/common"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -14,20 +18,24 @@ func sendTestLanguage(t *testing.T, l chan<- common.Language, code, name string)
 	close(l)
 }
 
-func sendMap(t *testing.T, m map[string]string) func(key string) <-chan common.Resource {
+func located(key, val string) common.LocatedResource {
+	return common.LocatedResource{common.Resource{key, val}, common.Origin{}, nil}
+}
+
+func sendMap(t *testing.T, m map[string]string) func(string, func(error)) <-chan common.LocatedResource {
 	local := m
 
-	fill := func(c chan<- common.Resource) {
+	fill := func(c chan<- common.LocatedResource) {
 		defer close(c)
 		if local != nil {
 			for key, entry := range local {
-				c <- common.Resource{key, entry}
+				c <- located(key, entry)
 			}
 		}
 	}
 
-	f := func(_ string) <-chan common.Resource {
-		c := make(chan common.Resource)
+	f := func(_ string, _ func(error)) <-chan common.LocatedResource {
+		c := make(chan common.LocatedResource)
 		go fill(c)
 		return c
 	}
@@ -36,48 +44,57 @@ func sendMap(t *testing.T, m map[string]string) func(key string) <-chan common.R
 }
 
 func TestSingleRegisterLanguage(t *testing.T) {
+	u := New()
+	defer u.Close()
+
 	l := make(chan common.Language)
 
 	go sendTestLanguage(t, l, "t1", "Testing 1")
 
-	Register(l, func(key string) <-chan common.Resource {
+	u.Register(nil, l, func(key string, _ func(error)) <-chan common.LocatedResource {
 		t.FailNow()
 		return nil
 	})
 
-	internalPtr := universe["t1"]
+	internalPtr := u.language("t1")
 	if internalPtr == nil ||
-		internalPtr.displayName != "Testing 1" ||
-		len(internalPtr.pendingFetches) != 1 ||
-		internalPtr.runningFetches != 0 {
+		internalPtr.info().DisplayName != "Testing 1" ||
+		len(internalPtr.pending) != 1 ||
+		internalPtr.loading != nil {
 		t.FailNow()
 	}
 }
 
 func TestRegisterAndActivateEmpty(t *testing.T) {
+	u := New()
+	defer u.Close()
+
 	l := make(chan common.Language)
 
 	go sendTestLanguage(t, l, "t2", "Testing 2")
 
-	Register(l, sendMap(t, nil))
-	Activate("t2")
+	u.Register(nil, l, sendMap(t, nil))
+	u.Activate("t2")
 
-	internalPtr := universe["t2"]
+	internalPtr := u.language("t2")
 	if internalPtr == nil ||
-		internalPtr.displayName != "Testing 2" ||
-		len(internalPtr.pendingFetches) != 0 ||
-		internalPtr.runningFetches != 0 {
+		internalPtr.info().DisplayName != "Testing 2" ||
+		len(internalPtr.pending) != 0 ||
+		internalPtr.loading != nil {
 		t.Log("Bad internal result: ", internalPtr)
 		t.FailNow()
 	}
 }
 
 func TestRegisterAndActivateWithSomeValues(t *testing.T) {
+	u := New()
+	defer u.Close()
+
 	l := make(chan common.Language)
 
 	go sendTestLanguage(t, l, "t3", "Testing 3")
 
-	Register(l, sendMap(t, map[string]string{
+	u.Register(nil, l, sendMap(t, map[string]string{
 		"a": "aaa",
 		"b": "abc",
 	}))
@@ -86,74 +103,80 @@ func TestRegisterAndActivateWithSomeValues(t *testing.T) {
 
 	go sendTestLanguage(t, l, "t3", "Testing 3")
 
-	Register(l, sendMap(t, map[string]string{
+	u.Register(nil, l, sendMap(t, map[string]string{
 		"c": "xxx",
 		"d": "xyz",
 	}))
 
-	Activate("t3")
+	u.Activate("t3")
 
-	internalPtr := universe["t3"]
+	internalPtr := u.language("t3")
 	if internalPtr == nil ||
-		internalPtr.displayName != "Testing 3" ||
-		len(internalPtr.pendingFetches) != 0 ||
-		internalPtr.runningFetches != 0 {
+		internalPtr.info().DisplayName != "Testing 3" ||
+		len(internalPtr.pending) != 0 ||
+		internalPtr.loading != nil {
 		t.Log("Bad internal result: ", internalPtr)
 		t.FailNow()
 	}
 
-	if internalPtr.entries[""]["a"] != "aaa" ||
-		internalPtr.entries[""]["b"] != "abc" ||
-		internalPtr.entries[""]["c"] != "xxx" ||
-		internalPtr.entries[""]["d"] != "xyz" {
-		t.Log("Entries are ", internalPtr.entries)
+	if internalPtr.snapshot().entries[""]["a"].value != "aaa" ||
+		internalPtr.snapshot().entries[""]["b"].value != "abc" ||
+		internalPtr.snapshot().entries[""]["c"].value != "xxx" ||
+		internalPtr.snapshot().entries[""]["d"].value != "xyz" {
+		t.Log("Entries are ", internalPtr.snapshot().entries)
 		t.FailNow()
 	}
 }
 
 func TestActivateTwice(t *testing.T) {
+	u := New()
+	defer u.Close()
+
 	l := make(chan common.Language)
 
 	go sendTestLanguage(t, l, "t4", "Testing 4")
 
-	Register(l, sendMap(t, map[string]string{
+	u.Register(nil, l, sendMap(t, map[string]string{
 		"a": "aaa",
 		"b": "abc",
 	}))
 
-	Activate("t4")
-	Activate("t4")
+	u.Activate("t4")
+	u.Activate("t4")
 
-	internalPtr := universe["t4"]
+	internalPtr := u.language("t4")
 	if internalPtr == nil ||
-		internalPtr.displayName != "Testing 4" ||
-		len(internalPtr.pendingFetches) != 0 ||
-		internalPtr.runningFetches != 0 {
+		internalPtr.info().DisplayName != "Testing 4" ||
+		len(internalPtr.pending) != 0 ||
+		internalPtr.loading != nil {
 		t.Log("Bad internal result: ", internalPtr)
 		t.FailNow()
 	}
 
-	if internalPtr.entries[""]["a"] != "aaa" ||
-		internalPtr.entries[""]["b"] != "abc" {
-		t.Log("Entries are ", internalPtr.entries)
+	if internalPtr.snapshot().entries[""]["a"].value != "aaa" ||
+		internalPtr.snapshot().entries[""]["b"].value != "abc" {
+		t.Log("Entries are ", internalPtr.snapshot().entries)
 		t.FailNow()
 	}
 }
 
 func TestBlockConcurringActivate(t *testing.T) {
+	u := New()
+	defer u.Close()
+
 	l := make(chan common.Language)
 
 	go sendTestLanguage(t, l, "t4a", "Testing 4a")
 
-	f := func(key string) <-chan common.Resource {
-		c := make(chan common.Resource)
+	f := func(key string, _ func(error)) <-chan common.LocatedResource {
+		c := make(chan common.LocatedResource)
 
 		go func() {
-			c <- common.Resource{"q", "b"}
+			c <- located("q", "b")
 			time.Sleep(time.Second / 10)
-			c <- common.Resource{"w", "c"}
+			c <- located("w", "c")
 			time.Sleep(time.Second / 10)
-			c <- common.Resource{"e", "d"}
+			c <- located("e", "d")
 			time.Sleep(time.Second / 10)
 			close(c)
 		}()
@@ -164,21 +187,24 @@ func TestBlockConcurringActivate(t *testing.T) {
 	for i := 0; i < 100; i++ {
 
 		if i%50 == 0 {
-			Register(l, f)
+			u.Register(nil, l, f)
 		}
 
-		go Activate("t4a")
+		go u.Activate("t4a")
 
 		time.Sleep(10 * time.Millisecond)
 	}
 }
 
 func TestGetResource(t *testing.T) {
+	u := New()
+	defer u.Close()
+
 	l := make(chan common.Language)
 
 	go sendTestLanguage(t, l, "t5", "Testing 5")
 
-	Register(l, sendMap(t, map[string]string{
+	u.Register(nil, l, sendMap(t, map[string]string{
 		"a": "aaa",
 		"b": "abc",
 	}))
@@ -186,67 +212,679 @@ func TestGetResource(t *testing.T) {
 	var val string
 	var err error
 
-	Activate("t5")
+	u.Activate("t5")
 
-	val, err = Request("t5", "a", false)
+	val, err = u.Request("t5", "a", false)
 
 	if val != "aaa" || err != nil {
-		t.Errorf("Got %v:%v, with entry %#v\n", val, err, universe["t5"])
+		t.Errorf("Got %v:%v, with entry %#v\n", val, err, u.language("t5"))
 	}
 
-	val, err = Request("t5", "b", false)
+	val, err = u.Request("t5", "b", false)
 
 	if val != "abc" || err != nil {
-		t.Errorf("Got %v:%v, with entry %#v\n", val, err, universe["t5"])
+		t.Errorf("Got %v:%v, with entry %#v\n", val, err, u.language("t5"))
 	}
 }
 
 func TestUpdateResource(t *testing.T) {
+	u := New()
+	defer u.Close()
+
 	l := make(chan common.Language)
 
 	go sendTestLanguage(t, l, "t6", "Testing 6")
 
-	Register(l, sendMap(t, map[string]string{
+	u.Register(nil, l, sendMap(t, map[string]string{
 		"a": "aaa",
 		"b": "abc",
 	}))
-	Activate("t6")
-	val, err := Request("t6", "a", false)
+	u.Activate("t6")
+	val, err := u.Request("t6", "a", false)
 
 	if val != "aaa" || err != nil {
-		t.Errorf("Got %v:%v, with entry %#v\n", val, err, universe["t5"])
+		t.Errorf("Got %v:%v, with entry %#v\n", val, err, u.language("t5"))
 	}
 
-	Update("t6", "b", "any")
+	u.Update("t6", "b", "any")
 
-	val, err = Request("t6", "b", false)
+	val, err = u.Request("t6", "b", false)
 
 	if val != "any" || err != nil {
-		t.Errorf("Got %v:%v, with entry %#v\n", val, err, universe["t5"])
+		t.Errorf("Got %v:%v, with entry %#v\n", val, err, u.language("t5"))
 	}
 }
 
 func TestList(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	for _, code := range []string{"t1", "t2", "t3"} {
+		l := make(chan common.Language)
+		go sendTestLanguage(t, l, code, "Testing "+code)
+		u.Register(nil, l, sendMap(t, nil))
+	}
+
 	m := make(map[string]string)
-	for _, value := range List() {
+	for _, value := range u.List() {
 		m[value.Code] = value.DisplayName
 	}
 
-	if len(m) != 7 {
+	if len(m) != 3 {
 		t.Log(len(m))
 		t.FailNow()
 	}
 
-	for key := range m {
+	for key, name := range m {
 		if key != "t1" &&
 			key != "t2" &&
-			key != "t3" &&
-			key != "t4" &&
-			key != "t4a" &&
-			key != "t5" &&
-			key != "t6" {
+			key != "t3" ||
+			name != "Testing "+key {
 			t.Log(key)
 			t.FailNow()
 		}
 	}
 }
+
+func TestIndependentUniverses(t *testing.T) {
+	u1, u2 := New(), New()
+	defer u1.Close()
+	defer u2.Close()
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t8", "Testing 8")
+	u1.Register(nil, l, sendMap(t, map[string]string{"a": "aaa"}))
+
+	if ok, _ := u1.Activate("t8"); !ok {
+		t.Error(u1.List())
+	}
+
+	if ok, _ := u2.Activate("t8"); ok {
+		t.Error(u2.List())
+	}
+
+	if val, err := u2.Request("t8", "a", false); err == nil {
+		t.Error(val)
+	}
+}
+
+func TestRequestFallbacks(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	for code, m := range map[string]map[string]string{
+		"t7":    {"a": "base a", "p:b": "base b"},
+		"t7-x1": {"a": "regional a"},
+	} {
+		l := make(chan common.Language)
+		go sendTestLanguage(t, l, code, code)
+		u.Register(nil, l, sendMap(t, m))
+		u.Activate(code)
+	}
+
+	if val, err := u.Request("t7-x1", "a", false, "t7"); val != "regional a" || err != nil {
+		t.Error(val, err)
+	}
+
+	if val, err := u.Request("t7-x1", "q:b", true, "t7"); val != "q:b" || err == nil {
+		t.Error(val, err)
+	}
+
+	if val, err := u.Request("t7-x1", "p:b", true, "t7"); val != "base b" || err != nil {
+		t.Error(val, err)
+	}
+
+	bundle := u.RequestBundle("t7-x1", "", false, "t7")
+	if len(bundle) != 1 || bundle["a"] != "regional a" {
+		t.Error(bundle)
+	}
+}
+
+func sendFailing(resources map[string]string, errs ...error) func(string, func(error)) <-chan common.LocatedResource {
+	return func(_ string, report func(error)) <-chan common.LocatedResource {
+		c := make(chan common.LocatedResource)
+
+		go func() {
+			defer close(c)
+			for _, err := range errs {
+				report(err)
+			}
+			for key, val := range resources {
+				c <- located(key, val)
+			}
+		}()
+
+		return c
+	}
+}
+
+func TestReportedErrors(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	reported := make(chan error, 2)
+	u.SetErrorHandler(func(err error) {
+		reported <- err
+	})
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t9", "Testing 9")
+	u.Register(nil, l, sendFailing(map[string]string{"a": "aaa"}, errors.New("broken")))
+
+	if ok, err := u.Activate("t9"); !ok || err != nil {
+		t.Error(ok, err)
+	}
+
+	if err := <-reported; err.Error() != "broken" {
+		t.Error(err)
+	}
+
+	if errs := u.Errors("t9"); len(errs) != 1 {
+		t.Error(errs)
+	}
+
+	if val, err := u.Request("t9", "a", false); val != "aaa" || err != nil {
+		t.Error(val, err)
+	}
+}
+
+func TestStrictActivation(t *testing.T) {
+	u := New()
+	defer u.Close()
+	u.SetStrict(true)
+
+	l := make(chan common.Language, 2)
+	l <- common.Language{Code: "t10", DisplayName: "Testing 10"}
+	l <- common.Language{Code: "t11", DisplayName: "Testing 11"}
+	close(l)
+	u.Register(nil, l, func(code string, report func(error)) <-chan common.LocatedResource {
+		if code == "t10" {
+			return sendFailing(nil, errors.New("broken"), errors.New("also broken"))(code, report)
+		}
+		return sendFailing(nil)(code, report)
+	})
+
+	_, err := u.Activate("t10")
+	if errs, ok := err.(common.LoadErrors); !ok || len(errs) != 2 {
+		t.Error(err)
+	}
+
+	// the failure persists for later activations
+	if _, err := u.Activate("t10"); err == nil {
+		t.Error(err)
+	}
+
+	if _, err := u.Activate("t11"); err != nil {
+		t.Error(err)
+	}
+
+	// activating a loaded language never waits for the writers of the universe
+	u.lock.Lock()
+	activated := make(chan error)
+	go func() {
+		_, err := u.Activate("t10")
+		activated <- err
+	}()
+
+	select {
+	case err := <-activated:
+		if err == nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Error("activation blocked by the lock of the universe")
+	}
+	u.lock.Unlock()
+}
+
+// a fetch whose provider never closes its channel
+func sendHanging(hang chan bool) func(string, func(error)) <-chan common.LocatedResource {
+	return func(_ string, _ func(error)) <-chan common.LocatedResource {
+		c := make(chan common.LocatedResource)
+
+		go func() {
+			c <- located("a", "aaa")
+			<-hang
+			close(c)
+		}()
+
+		return c
+	}
+}
+
+func TestActivateContextDeadline(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	hang := make(chan bool)
+	defer close(hang)
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t12", "Testing 12")
+	u.Register(nil, l, sendHanging(hang))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if ok, err := u.ActivateContext(ctx, "t12"); !ok || err != context.DeadlineExceeded {
+		t.Error(ok, err)
+	}
+}
+
+func TestAbandon(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	hang := make(chan bool)
+	defer close(hang)
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t13", "Testing 13")
+	u.Register(nil, l, sendHanging(hang))
+
+	activated := make(chan bool)
+	go func() {
+		u.Activate("t13")
+		close(activated)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	u.Abandon("t13")
+
+	select {
+	case <-activated:
+	case <-time.After(time.Second):
+		t.Fatal("activation still blocked after abandoning")
+	}
+
+	if errs := u.Errors("t13"); len(errs) != 1 || errs[0].(*common.LoadError).Err != ErrAbandoned {
+		t.Error(errs)
+	}
+
+	if val, err := u.Request("t13", "a", false); err == nil {
+		t.Error(val)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	u := New()
+	defer u.Close()
+	u.SetFetchTimeout(50 * time.Millisecond)
+
+	hang := make(chan bool)
+	defer close(hang)
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t14", "Testing 14")
+	u.Register(nil, l, sendHanging(hang))
+
+	if ok, err := u.Activate("t14"); !ok || err != nil {
+		t.Error(ok, err)
+	}
+
+	if errs := u.Errors("t14"); len(errs) != 1 {
+		t.Error(errs)
+	}
+}
+
+func TestReload(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	value := "old"
+	release := make(chan bool, 1)
+	release <- true
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t15", "Testing 15")
+	u.Register(nil, l, func(string, func(error)) <-chan common.LocatedResource {
+		c := make(chan common.LocatedResource)
+		go func(value string) {
+			defer close(c)
+			c <- located("a", value)
+			<-release
+			c <- located("b:c", value)
+		}(value)
+
+		return c
+	})
+
+	u.Activate("t15")
+	value = "new"
+
+	reloaded := make(chan error)
+	go func() {
+		reloaded <- u.Reload("t15")
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if a, _ := u.Request("t15", "a", false); a != "old" {
+		t.Error("lookup during reload sees", a)
+	}
+	if c, _ := u.Request("t15", "b:c", false); c != "old" {
+		t.Error("lookup during reload sees", c)
+	}
+
+	release <- true
+	if err := <-reloaded; err != nil {
+		t.Error(err)
+	}
+
+	if a, _ := u.Request("t15", "a", false); a != "new" {
+		t.Error(a)
+	}
+	if c, _ := u.Request("t15", "b:c", false); c != "new" {
+		t.Error(c)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	first, second := new(int), new(int)
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t16", "Testing 16")
+	u.Register(first, l, sendMap(t, map[string]string{"a": "first", "b": "first"}))
+
+	l = make(chan common.Language)
+	go sendTestLanguage(t, l, "t16", "Testing 16")
+	u.Register(second, l, sendMap(t, map[string]string{"a": "second"}))
+
+	u.Activate("t16")
+	if a, _ := u.Request("t16", "a", false); a != "second" {
+		t.Error(a)
+	}
+
+	u.Unregister(second)
+	if a, _ := u.Request("t16", "a", false); a != "first" {
+		t.Error(a)
+	}
+
+	u.Unregister(first)
+	if ok, _ := u.Activate("t16"); ok || len(u.List()) != 0 {
+		t.Error("language still listed after unregistering all of its sources", u.List())
+	}
+}
+
+func TestUnregisterCompactsStore(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	kept, gone := new(int), new(int)
+	resources := map[string]string{"p:a": "kept", "p:c": "dropped on reload"}
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t16a", "Testing 16a")
+	u.Register(kept, l, sendMap(t, resources))
+
+	l = make(chan common.Language)
+	go sendTestLanguage(t, l, "t16a", "Testing 16a")
+	u.Register(gone, l, sendMap(t, map[string]string{"p:b": "gone"}))
+
+	u.Activate("t16a")
+	u.Update("t16a", "overridden", "value")
+	if _, ok := u.store.keys["overridden"]; ok {
+		t.Error("override key interned")
+	}
+
+	u.Unregister(gone)
+	if _, ok := u.store.keys["b"]; ok {
+		t.Error("key of an unregistered source kept")
+	}
+	if _, ok := u.store.keys["a"]; !ok {
+		t.Error("key of a registered source dropped")
+	}
+
+	for _, l := range u.store.locations.Load().([]location) {
+		if l.owner > 1 {
+			t.Error("location of an unregistered source kept", l)
+		}
+	}
+
+	if a, _ := u.Request("t16a", "p:a", false); a != "kept" {
+		t.Error(a)
+	}
+
+	delete(resources, "p:c")
+	if err := u.Reload("t16a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := u.store.keys["c"]; ok {
+		t.Error("key dropped on reload kept")
+	}
+	if _, ok := u.store.keys["a"]; !ok {
+		t.Error("key kept on reload dropped")
+	}
+}
+
+func sendDelayed(delay time.Duration, m map[string]string) func(string, func(error)) <-chan common.LocatedResource {
+	return func(string, func(error)) <-chan common.LocatedResource {
+		c := make(chan common.LocatedResource)
+		go func() {
+			defer close(c)
+			time.Sleep(delay)
+			for key, val := range m {
+				c <- located(key, val)
+			}
+		}()
+
+		return c
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		u := New()
+
+		// the sources registered first finish last
+		for j := 0; j < 3; j++ {
+			l := make(chan common.Language)
+			go sendTestLanguage(t, l, "t17", "Testing 17")
+			u.Register(nil, l, sendDelayed(time.Duration(3-j)*time.Millisecond, map[string]string{"a": strconv.Itoa(j)}))
+		}
+
+		u.Activate("t17")
+		if a, _ := u.Request("t17", "a", false); a != "2" {
+			t.Fatal("iteration", i, "resolved to", a)
+		}
+
+		u.Close()
+	}
+}
+
+func TestPriority(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t18", "Testing 18")
+	u.RegisterPriority(nil, 1, l, sendMap(t, map[string]string{"a": "high"}))
+
+	l = make(chan common.Language)
+	go sendTestLanguage(t, l, "t18", "Testing 18")
+	u.Register(nil, l, sendMap(t, map[string]string{"a": "low"}))
+
+	u.Activate("t18")
+	if a, _ := u.Request("t18", "a", false); a != "high" {
+		t.Error(a)
+	}
+
+	// registered after activation, but still below the loaded source
+	l = make(chan common.Language)
+	go sendTestLanguage(t, l, "t18", "Testing 18")
+	u.Register(nil, l, sendMap(t, map[string]string{"a": "later", "b": "later"}))
+
+	u.Activate("t18")
+	if a, _ := u.Request("t18", "a", false); a != "high" {
+		t.Error(a)
+	}
+	if b, _ := u.Request("t18", "b", false); b != "later" {
+		t.Error(b)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	u.Update("t19", "a", "early")
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t19", "Testing 19")
+	u.Register(nil, l, sendMap(t, map[string]string{"a": "aaa", "b:c": "ccc"}))
+	u.Activate("t19")
+
+	if a, _ := u.Request("t19", "a", false); a != "early" {
+		t.Error(a)
+	}
+
+	u.ApplyOverrides("t19", map[string]string{"b:c": "overridden", "b:d": "added"}, "a")
+	if err := u.Reload("t19"); err != nil {
+		t.Error(err)
+	}
+
+	expect := map[string]string{"a": "aaa", "b:c": "overridden", "b:d": "added"}
+	for key, val := range expect {
+		if str, _ := u.Request("t19", key, false); str != val {
+			t.Error(key, str)
+		}
+	}
+
+	if overrides := u.Overrides("t19"); len(overrides) != 2 || overrides["b:d"] != "added" {
+		t.Error(overrides)
+	}
+
+	u.ClearOverrides("t19")
+	if str, _ := u.Request("t19", "b:c", false); str != "ccc" || len(u.Overrides("t19")) != 0 {
+		t.Error(str, u.Overrides("t19"))
+	}
+}
+
+func TestWatch(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	changes := make(chan common.Change, 10)
+	stop := u.Watch(func(change common.Change) {
+		changes <- change
+	})
+
+	value := "v1"
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t20", "Testing 20")
+	u.Register(nil, l, func(code string, report func(error)) <-chan common.LocatedResource {
+		return sendMap(t, map[string]string{"a": value, "b:c": "fixed"})(code, report)
+	})
+
+	next := func() common.Change {
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(time.Second):
+			t.Fatal("no change delivered")
+		}
+		return common.Change{}
+	}
+
+	u.Activate("t20")
+	if change := next(); change.Code != "t20" || change.Reason != common.ChangeLoaded || len(change.Keys) != 2 || change.Keys[1] != "b:c" {
+		t.Error(change)
+	}
+
+	u.Update("t20", "b:d", "new")
+	if change := next(); change.Reason != common.ChangeOverridden || len(change.Keys) != 1 || change.Keys[0] != "b:d" || change.Prefixes[0] != "b" {
+		t.Error(change)
+	}
+
+	value = "v2"
+	u.Reload("t20")
+	if change := next(); change.Reason != common.ChangeReloaded || len(change.Keys) != 1 || change.Keys[0] != "a" {
+		t.Error(change)
+	}
+
+	stop()
+	u.Update("t20", "a", "ignored")
+	select {
+	case change := <-changes:
+		t.Error("change delivered after stopping", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestKeysAndSubtree(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t21", "Testing 21")
+	u.Register(nil, l, sendMap(t, map[string]string{"menu": "Menu", "menu:file": "File", "menu:file:open": "Open", "menus:x": "x"}))
+
+	l = make(chan common.Language)
+	go sendTestLanguage(t, l, "t22", "Testing 22")
+	u.Register(nil, l, sendMap(t, map[string]string{"menu:file": "Fallback", "menu:edit": "Edit"}))
+
+	u.Activate("t21")
+	u.Activate("t22")
+
+	keys := u.Keys("t21", "menu", "t22")
+	if strings.Join(keys, " ") != "menu:edit menu:file menu:file:open" {
+		t.Error(keys)
+	}
+
+	if all := u.Keys("t21", ""); len(all) != 4 {
+		t.Error(all)
+	}
+
+	if subtree := u.Subtree("t21", "menu:file", "t22"); len(subtree) != 1 || subtree["menu:file:open"] != "Open" {
+		t.Error(subtree)
+	}
+}
+
+func TestRegisterBundles(t *testing.T) {
+	u := New()
+	defer u.Close()
+
+	bundles := map[string]map[string]string{
+		"":  {"a": "lazy"},
+		"b": {"b:c": "lazy", "b:d": "lazy"},
+	}
+	fetched := make(chan string, 10)
+	l := make(chan common.Language)
+	go sendTestLanguage(t, l, "t23", "Testing 23")
+	u.RegisterBundles(nil, 0, l, func(string) []string {
+		return []string{"", "b"}
+	}, func(code, prefix string, report func(error)) <-chan common.LocatedResource {
+		fetched <- prefix
+		return sendMap(t, bundles[prefix])(code, report)
+	})
+
+	l = make(chan common.Language)
+	go sendTestLanguage(t, l, "t23", "Testing 23")
+	u.RegisterPriority(nil, 1, l, sendMap(t, map[string]string{"b:c": "eager"}))
+
+	if _, err := u.Activate("t23"); err != nil || len(fetched) != 0 {
+		t.Error(err, len(fetched))
+	}
+	if c, _ := u.Request("t23", "b:c", false); c != "eager" {
+		t.Error(c)
+	}
+
+	u.ActivateContext(context.Background(), "t23", Scope{"b", false})
+	if len(fetched) != 2 {
+		t.Error(len(fetched))
+	}
+	if c, _ := u.Request("t23", "b:c", false); c != "eager" {
+		t.Error(c)
+	}
+	if d, _ := u.Request("t23", "b:d", false); d != "lazy" {
+		t.Error(d)
+	}
+
+	// loaded scopes are not fetched again
+	u.ActivateContext(context.Background(), "t23", Scope{"b", false}, Scope{"", false})
+	if len(fetched) != 2 {
+		t.Error(len(fetched))
+	}
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	types "github.com/beatgammit/ginta/common"
+	"sync/atomic"
+)
+
+// The place resources are defined at, apart from their line: the serial of the source defining them,
+// and the provider and file their origin names. The zero location stands for overrides. Locations
+// refer to sources by serial, so that unregistered sources and their providers can be collected
+type location struct {
+	owner    uint32
+	provider string
+	file     string
+}
+
+// The storage shared by all languages of a universe. Bundle prefixes and keys are interned, so that
+// languages defining the same keys share their strings, and entries refer to the place they are
+// defined at by an index into a table of locations, rather than by an origin of their own. Both
+// tables grow as sources are loaded. Keys are compacted when a rebuild drops some, locations when
+// sources are unregistered. Writers must
+// hold the lock of the universe, readers of the locations never block. Readers must load the
+// locations after the snapshot whose entries they look up
+type store struct {
+	keys map[string]string
+	ids  map[location]uint32
+	list []location
+	// holds the []location readers see, a prefix of list
+	locations atomic.Value
+}
+
+func newStore() *store {
+	s := &store{keys: make(map[string]string), ids: make(map[location]uint32), list: []location{{}}}
+	s.ids[location{}] = 0
+	s.locations.Store(s.list)
+
+	return s
+}
+
+// returns the interned copy of a prefix or key
+func (s *store) intern(str string) string {
+	if interned, ok := s.keys[str]; ok {
+		return interned
+	}
+
+	// copied, as the string may be cut from a longer one that is not needed any longer
+	str = string([]byte(str))
+	s.keys[str] = str
+	return str
+}
+
+// returns the index of the location of resources of the source with the serial, and an origin
+func (s *store) locate(serial uint32, origin types.Origin) uint32 {
+	l := location{serial, origin.Provider, origin.File}
+	if id, ok := s.ids[l]; ok {
+		return id
+	}
+
+	id := uint32(len(s.list))
+	s.list = append(s.list, l)
+	s.ids[l] = id
+	s.locations.Store(s.list)
+
+	return id
+}
+
+// drops the strings none of the live snapshots uses, and clears the locations of the removed sources.
+// Indices of cleared locations are not reused, as readers may still look up entries of snapshots
+// published before. Both tables are copied, never modified in place
+func (s *store) compact(live []*snapshot, removed map[uint32]bool) {
+	keys := make(map[string]string)
+	for _, snap := range live {
+		for prefix, b := range snap.entries {
+			if interned, ok := s.keys[prefix]; ok {
+				keys[prefix] = interned
+			}
+			for key := range b {
+				if interned, ok := s.keys[key]; ok {
+					keys[key] = interned
+				}
+			}
+		}
+	}
+	s.keys = keys
+
+	list := append([]location{}, s.list...)
+	for i, l := range list {
+		if removed[l.owner] {
+			delete(s.ids, l)
+			list[i] = location{}
+		}
+	}
+	s.list = list
+	s.locations.Store(list)
+}
+
+// reports whether a snapshot replacing this one lacks any of its prefixes or keys
+func (s *snapshot) drops(next *snapshot) bool {
+	for prefix, b := range s.entries {
+		kept, ok := next.entries[prefix]
+		if !ok {
+			return true
+		}
+		for key := range b {
+			if _, ok := kept[key]; !ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// returns the origin of an entry, and false if it has none
+func (e entry) origin(locations []location) (types.Origin, bool) {
+	l := locations[e.location]
+	origin := types.Origin{l.provider, l.file, int(e.line)}
+	return origin, origin != types.Origin{}
+}
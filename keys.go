@@ -0,0 +1,128 @@
+package ginta
+
+import (
+	"context"
+	types "github.com/beatgammit/ginta/common"
+	"github.com/beatgammit/ginta/internal"
+	"sort"
+	"strings"
+)
+
+/*
+	A nested view of the resources below a prefix. Every segment of a hierarchical key is one
+	level of nesting: the values of a tree are either strings (resources) or trees (packages).
+	If a name denotes both a resource and a package, the value of the resource is stored in
+	the tree of the package under the empty name. Trees encode to JSON as nested objects, which
+	makes them suitable for shipping translations to front-end code.
+*/
+type Tree map[string]interface{}
+
+/*
+	Returns the full hierarchical keys of all resources of this locale below the prefix, sorted.
+	Keys only defined by the fallback chain of the locale are included. The empty prefix lists
+	all keys. Applies to the DefaultCatalog.
+
+	Example:
+		Locale("de").Keys("menu") // [menu:edit menu:file menu:file:open]
+*/
+func (l Locale) Keys(prefix string) []string {
+	return DefaultCatalog.Keys(l, prefix)
+}
+
+/*
+	Returns the full prefixes of the packages directly below the prefix, sorted. Applies to the
+	DefaultCatalog.
+
+	Example:
+		Locale("de").Children("menu") // [menu:file]
+*/
+func (l Locale) Children(prefix string) []string {
+	return DefaultCatalog.Children(l, prefix)
+}
+
+/*
+	Returns all resources of this locale below the prefix as a tree, relative to the prefix.
+	Resources missing in the locale are taken from its fallback chain. Applies to the
+	DefaultCatalog.
+
+	Example:
+		Locale("de").Subtree("menu") // Tree{"edit": "Bearbeiten", "file": Tree{"": "Datei", "open": "Öffnen"}}
+*/
+func (l Locale) Subtree(prefix string) Tree {
+	return DefaultCatalog.Subtree(l, prefix)
+}
+
+/*
+	Returns the keys of a locale below the prefix. See Locale.Keys
+*/
+func (c *Catalog) Keys(l Locale, prefix string) []string {
+	locale, chain, _ := c.activate(context.Background(), l, internal.Scope{prefix, true})
+	return c.universe.Keys(locale, prefix, chain...)
+}
+
+/*
+	Returns the packages of a locale directly below the prefix. See Locale.Children
+*/
+func (c *Catalog) Children(l Locale, prefix string) []string {
+	children := []string{}
+	seen := make(map[string]bool)
+	for _, key := range c.Keys(l, prefix) {
+		if segments := relative(key, prefix); len(segments) > 1 {
+			child := segments[0]
+			if prefix != "" {
+				child = prefix + types.ResourceKeySegmentSeparator + child
+			}
+
+			if !seen[child] {
+				seen[child] = true
+				children = append(children, child)
+			}
+		}
+	}
+
+	sort.Strings(children)
+	return children
+}
+
+/*
+	Returns the resources of a locale below the prefix as a tree. See Locale.Subtree
+*/
+func (c *Catalog) Subtree(l Locale, prefix string) Tree {
+	locale, chain, _ := c.activate(context.Background(), l, internal.Scope{prefix, true})
+
+	root := Tree{}
+	for key, val := range c.universe.Subtree(locale, prefix, chain...) {
+		tree := root
+		segments := relative(key, prefix)
+		for _, segment := range segments[:len(segments)-1] {
+			switch node := tree[segment].(type) {
+			case Tree:
+				tree = node
+			case string:
+				tree[segment] = Tree{"": node}
+				tree = tree[segment].(Tree)
+			default:
+				tree[segment] = Tree{}
+				tree = tree[segment].(Tree)
+			}
+		}
+
+		name := segments[len(segments)-1]
+		if node, ok := tree[name].(Tree); ok {
+			node[""] = val
+		} else {
+			tree[name] = val
+		}
+	}
+
+	return root
+}
+
+// splits a key below the prefix into the segments following the prefix
+func relative(key, prefix string) []string {
+	if prefix != "" {
+		key = key[len(prefix)+len(types.ResourceKeySegmentSeparator):]
+	}
+
+	return strings.Split(key, types.ResourceKeySegmentSeparator)
+}
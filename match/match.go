@@ -0,0 +1,361 @@
+/*
+Negotiates the locale to use for a client, given its weighted language preferences
+(usually from an Accept-Language header) and the languages an application supports.
+
+Preferences are tried in the order of their quality. For each preference, the closest
+supported language is determined by comparing the language tags subtag by subtag:
+The languages must be equal, scripts are compared after inferring the likely script of
+a tag (so zh-TW is written in Hant, while zh-CN and plain zh are written in Hans), and
+regions only add a minor distance. The result carries a Confidence, which allows
+callers to decide whether a weak match is good enough for them.
+
+Example:
+	m := match.New("en", "de", "zh-Hans", "zh-Hant")
+	locale, confidence := m.MatchHeader("zh-TW, de;q=0.8")
+	// locale = zh-Hant, confidence = match.High
+*/
+package match
+
+import (
+	"github.com/beatgammit/ginta"
+	"github.com/beatgammit/ginta/common"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Describes how well a negotiated locale fits the preferences of a client
+*/
+type Confidence int
+
+const (
+	// Nothing matched, the matcher's default locale was returned
+	No Confidence = iota
+	// The language matches, but in a different script (zh-Hans for zh-Hant)
+	Low
+	// The language and script match, but not the region or variants (de for de-AT)
+	High
+	// The language tags are equal
+	Exact
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case Low:
+		return "Low"
+	case High:
+		return "High"
+	case Exact:
+		return "Exact"
+	}
+
+	return "No"
+}
+
+/*
+A single, weighted language preference of a client
+*/
+type Preference struct {
+	Locale ginta.Locale
+	// The quality value of the preference, between 0 and 1
+	Quality float64
+}
+
+// Language range of an Accept-Language header that matches any language
+const Wildcard = "*"
+
+/*
+Parses the value of an Accept-Language header (RFC 7231) into a list of preferences, sorted by
+descending quality. Entries of equal quality keep their order. Entries with a quality of 0 are
+not acceptable to the client, and are omitted. Malformed entries are skipped, and reported by
+the returned error - the remaining preferences are still returned.
+*/
+func ParseAcceptLanguage(header string) ([]Preference, error) {
+	var err error
+	result := []Preference{}
+
+	for _, entry := range strings.Split(header, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		params := strings.Split(entry, ";")
+		pref := Preference{ginta.Locale(strings.TrimSpace(params[0])), 1}
+		valid := true
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			q, qErr := strconv.ParseFloat(param[2:], 64)
+			if valid = qErr == nil && q >= 0 && q <= 1; valid {
+				pref.Quality = q
+			}
+		}
+
+		if valid && pref.Locale != Wildcard {
+			_, tagErr := pref.Locale.Tag()
+			valid = tagErr == nil
+		}
+
+		if !valid {
+			if err == nil {
+				err = MalformedHeaderError(entry)
+			}
+		} else if pref.Quality > 0 {
+			pref.Locale = pref.Locale.Canonical()
+			result = append(result, pref)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Quality > result[j].Quality
+	})
+
+	return result, err
+}
+
+/*
+Error returned for malformed entries of an Accept-Language header. Prints the offending entry
+*/
+type MalformedHeaderError string
+
+func (e MalformedHeaderError) Error() string {
+	return "malformed language preference: " + string(e)
+}
+
+/*
+Selects the best supported locale for a list of preferences. A matcher is immutable, and may be
+used concurrently.
+*/
+type Matcher struct {
+	supported []ginta.Locale
+	tags      []common.Tag
+}
+
+/*
+Creates a new matcher for the supported locales. The first supported locale is the default,
+which is returned whenever no preference can be matched. Locales which are not well-formed
+language tags can only be matched exactly.
+*/
+func New(supported ...ginta.Locale) *Matcher {
+	m := &Matcher{
+		supported: make([]ginta.Locale, len(supported)),
+		tags:      make([]common.Tag, len(supported)),
+	}
+
+	for i, l := range supported {
+		m.supported[i] = l.Canonical()
+		m.tags[i], _ = l.Tag()
+	}
+
+	return m
+}
+
+/*
+Creates a new matcher for all languages currently registered with ginta. The DefaultLocale is the
+matcher's default if it is registered, the remaining languages are ordered by their code.
+*/
+func Registered() *Matcher {
+	codes := []string{}
+	for _, lang := range ginta.List() {
+		codes = append(codes, lang.Code)
+	}
+	sort.Strings(codes)
+
+	def := ginta.DefaultLocale.Canonical()
+	supported := []ginta.Locale{}
+	for _, code := range codes {
+		if l := ginta.Locale(code); l == def {
+			supported = append([]ginta.Locale{l}, supported...)
+		} else {
+			supported = append(supported, l)
+		}
+	}
+
+	return New(supported...)
+}
+
+/*
+Returns the supported locales of this matcher, default first
+*/
+func (m *Matcher) Supported() []ginta.Locale {
+	return append([]ginta.Locale{}, m.supported...)
+}
+
+/*
+Returns the best supported locale for the given preferences, which must be sorted by
+descending quality. The first preference with a match of at least High confidence wins.
+Failing that, the first preference with a Low confidence match wins. If nothing
+matches, the default locale is returned with No confidence. A matcher without any
+supported locales returns the ginta.DefaultLocale.
+*/
+func (m *Matcher) Match(prefs ...Preference) (ginta.Locale, Confidence) {
+	if len(m.supported) == 0 {
+		return ginta.DefaultLocale, No
+	}
+
+	result, confidence := m.supported[0], No
+	for _, pref := range prefs {
+		if pref.Locale == Wildcard {
+			if confidence == No {
+				confidence = Low
+			}
+			continue
+		}
+
+		if l, c := m.matchLocale(pref.Locale); c >= High {
+			return l, c
+		} else if c > confidence {
+			result, confidence = l, c
+		}
+	}
+
+	return result, confidence
+}
+
+/*
+Parses an Accept-Language header and matches its preferences. Malformed entries of the header
+are ignored.
+*/
+func (m *Matcher) MatchHeader(header string) (ginta.Locale, Confidence) {
+	prefs, _ := ParseAcceptLanguage(header)
+	return m.Match(prefs...)
+}
+
+func (m *Matcher) matchLocale(l ginta.Locale) (ginta.Locale, Confidence) {
+	l = l.Canonical()
+	tag, err := l.Tag()
+	best, bestDistance := -1, noMatch
+
+	for i, supported := range m.supported {
+		distance := noMatch
+		if supported == l {
+			distance = 0
+		} else if err == nil && !m.tags[i].IsZero() {
+			distance = tagDistance(tag, m.tags[i])
+		}
+
+		if distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+
+	switch {
+	case best < 0:
+		return "", No
+	case bestDistance == 0:
+		return m.supported[best], Exact
+	case bestDistance < scriptDistance:
+		return m.supported[best], High
+	}
+
+	return m.supported[best], Low
+}
+
+const (
+	variantDistance         = 1
+	containedRegionDistance = 2
+	parentRegionDistance    = 4
+	regionDistance          = 8
+	scriptDistance          = 50
+	noMatch                 = 1000
+)
+
+// calculates the distance between a desired and a supported tag
+func tagDistance(desired, supported common.Tag) int {
+	if desired.Language != supported.Language {
+		return noMatch
+	}
+
+	distance := 0
+
+	if a, b := likelyScript(desired), likelyScript(supported); a != b && a != "" && b != "" {
+		distance += scriptDistance
+	}
+
+	switch a, b := desired.Region, supported.Region; {
+	case a == b:
+	case a == "" || b == "":
+		distance += parentRegionDistance
+	case containedIn(a, b) || containedIn(b, a):
+		distance += containedRegionDistance
+	default:
+		distance += regionDistance
+	}
+
+	if strings.Join(desired.Variants, common.TagSeparator) != strings.Join(supported.Variants, common.TagSeparator) {
+		distance += variantDistance
+	}
+
+	if distance == 0 && desired.String() != supported.String() {
+		distance = variantDistance
+	}
+
+	return distance
+}
+
+// returns the script of a tag, inferring the likely script if none is specified
+func likelyScript(tag common.Tag) string {
+	if tag.Script != "" {
+		return tag.Script
+	}
+
+	if script, ok := likelyRegionScripts[tag.Language+common.TagSeparator+tag.Region]; ok {
+		return script
+	}
+
+	return likelyScripts[tag.Language]
+}
+
+// returns true if the macro region contains the region
+func containedIn(region, macroRegion string) bool {
+	if macroRegion == world {
+		return true
+	}
+
+	for _, r := range macroRegions[macroRegion] {
+		if r == region {
+			return true
+		}
+	}
+
+	return false
+}
+
+// likely scripts of languages whose script depends on the region
+var likelyRegionScripts = map[string]string{
+	"zh-TW": "Hant",
+	"zh-HK": "Hant",
+	"zh-MO": "Hant",
+	"sr-ME": "Latn",
+	"uz-AF": "Arab",
+	"pa-PK": "Arab",
+}
+
+// likely scripts of languages
+var likelyScripts = map[string]string{
+	"am": "Ethi", "ar": "Arab", "be": "Cyrl", "bg": "Cyrl", "bn": "Beng",
+	"cs": "Latn", "da": "Latn", "de": "Latn", "el": "Grek", "en": "Latn",
+	"es": "Latn", "fa": "Arab", "fi": "Latn", "fr": "Latn", "gu": "Gujr",
+	"he": "Hebr", "hi": "Deva", "hr": "Latn", "hu": "Latn", "hy": "Armn",
+	"id": "Latn", "it": "Latn", "ja": "Jpan", "ka": "Geor", "kk": "Cyrl",
+	"km": "Khmr", "kn": "Knda", "ko": "Kore", "lt": "Latn", "lv": "Latn",
+	"mk": "Cyrl", "ml": "Mlym", "mn": "Cyrl", "mr": "Deva", "nl": "Latn",
+	"no": "Latn", "pa": "Guru", "pl": "Latn", "pt": "Latn", "ro": "Latn",
+	"ru": "Cyrl", "sk": "Latn", "sl": "Latn", "sr": "Cyrl", "sv": "Latn",
+	"ta": "Taml", "te": "Telu", "th": "Thai", "tr": "Latn", "uk": "Cyrl",
+	"ur": "Arab", "uz": "Latn", "vi": "Latn", "yi": "Hebr", "zh": "Hans",
+}
+
+// UN M.49 code of the world region, which contains all regions
+const world = "001"
+
+// macro regions (UN M.49) commonly used in language tags, and the regions they contain
+var macroRegions = map[string][]string{
+	"419": {"AR", "BO", "BR", "BZ", "CL", "CO", "CR", "CU", "DO", "EC", "GT", "HN", "HT", "MX", "NI", "PA", "PE", "PR", "PY", "SV", "UY", "VE"},
+	"150": {"AT", "BE", "BG", "CH", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GB", "GR", "HR", "HU", "IE", "IT", "LT", "LU", "LV", "MT", "NL", "NO", "PL", "PT", "RO", "SE", "SI", "SK"},
+}
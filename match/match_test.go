@@ -0,0 +1,97 @@
+package match
+
+import (
+	"github.com/beatgammit/ginta"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	prefs, err := ParseAcceptLanguage("da, en_gb;q=0.8, en;q=0.7, de;q=0, fr;q=0.8")
+	if err != nil {
+		t.Error(err)
+	}
+
+	expect := []Preference{{"da", 1}, {"en-GB", 0.8}, {"fr", 0.8}, {"en", 0.7}}
+	if len(prefs) != len(expect) {
+		t.Fatal(prefs)
+	}
+
+	for i, pref := range expect {
+		if prefs[i] != pref {
+			t.Error(i, prefs[i])
+		}
+	}
+}
+
+func TestParseMalformedAcceptLanguage(t *testing.T) {
+	prefs, err := ParseAcceptLanguage("en;q=2, 1234, de;q=0.5")
+
+	if _, ok := err.(MalformedHeaderError); !ok {
+		t.Error(err)
+	}
+
+	if len(prefs) != 1 || prefs[0].Locale != "de" {
+		t.Error(prefs)
+	}
+}
+
+func TestMatchScript(t *testing.T) {
+	m := New("en", "zh-Hans", "zh-Hant")
+
+	for header, expect := range map[string]ginta.Locale{
+		"zh-TW":      "zh-Hant",
+		"zh-HK":      "zh-Hant",
+		"zh-CN":      "zh-Hans",
+		"zh":         "zh-Hans",
+		"zh-Hant-TW": "zh-Hant",
+	} {
+		if l, c := m.MatchHeader(header); l != expect || c != High {
+			t.Error(header, l, c)
+		}
+	}
+}
+
+func TestMatchConfidence(t *testing.T) {
+	m := New("en", "de-DE", "es-419", "sr-Latn")
+
+	expect := []struct {
+		header     string
+		locale     ginta.Locale
+		confidence Confidence
+	}{
+		{"de-DE", "de-DE", Exact},
+		{"DE_de", "de-DE", Exact},
+		{"de-AT", "de-DE", High},
+		{"es-MX", "es-419", High},
+		{"sr", "sr-Latn", Low},
+		{"fr", "en", No},
+		{"*", "en", Low},
+		{"", "en", No},
+		{"fr, sr;q=0.9, de;q=0.5", "de-DE", High},
+		{"fr, sr;q=0.9", "sr-Latn", Low},
+	}
+
+	for _, e := range expect {
+		if l, c := m.MatchHeader(e.header); l != e.locale || c != e.confidence {
+			t.Error(e.header, l, c)
+		}
+	}
+}
+
+func TestMatchPrefersCloserRegion(t *testing.T) {
+	m := New("es-ES", "es-419", "es")
+
+	if l, c := m.MatchHeader("es-AR"); l != "es-419" || c != High {
+		t.Error(l, c)
+	}
+
+	if l, c := m.MatchHeader("es-CL, es-ES;q=0.5"); l != "es-419" || c != High {
+		t.Error(l, c)
+	}
+}
+
+func TestEmptyMatcher(t *testing.T) {
+	if l, c := New().MatchHeader("de"); l != ginta.DefaultLocale || c != No {
+		t.Error(l, c)
+	}
+}
@@ -0,0 +1,85 @@
+package ginta
+
+import (
+	"encoding/json"
+	"errors"
+	types "github.com/beatgammit/ginta/common"
+)
+
+/*
+	Decides what a failed lookup of a single resource (See GetResource and ResolveResource)
+	returns. The handler receives the locale and the error describing the keys tried, and
+	returns the value and error passed on to the caller. Handlers may log the miss, substitute
+	a placeholder, ask a translation backend, or fail loudly in tests:
+
+		c.SetMissHandler(func(l ginta.Locale, err *common.NotFoundError) (string, error) {
+			log.Printf("%s: missing %s", l, err.Key)
+			return "[" + err.Key + "]", nil
+		})
+
+	Handlers may be called from any goroutine, concurrently.
+*/
+type MissHandler func(l Locale, err *types.NotFoundError) (string, error)
+
+/*
+	A miss handler that panics with the error, to make missing resources fail tests
+*/
+func PanicOnMiss(_ Locale, err *types.NotFoundError) (string, error) {
+	panic(err)
+}
+
+/*
+	Sets the handler of failed lookups of the catalog. A nil handler, the default, returns the
+	key and the error.
+*/
+func (c *Catalog) SetMissHandler(handler MissHandler) {
+	c.missHandler.Store(handler)
+}
+
+/*
+	Returns the lookup statistics of a locale: the number of resources found in the locale
+	itself or its fallback chain, and the number of misses. Only lookups of single resources
+	(See GetResource and ResolveResource) are counted.
+*/
+func (c *Catalog) Stats(l Locale) types.Stats {
+	return c.universe.Stats()[string(l.Canonical())]
+}
+
+/*
+	The lookup statistics of all registered locales of a catalog that have been looked up (See
+	Catalog.Stats), as a JSON object keyed by locale. StatsVar implements expvar.Var, so that
+	applications can publish the statistics, which this package never does on its own:
+
+		expvar.Publish("ginta", ginta.DefaultCatalog.StatsVar())
+*/
+type StatsVar struct {
+	catalog *Catalog
+}
+
+func (v StatsVar) String() string {
+	encoded, err := json.Marshal(v.catalog.universe.Stats())
+	if err != nil {
+		return "{}"
+	}
+
+	return string(encoded)
+}
+
+/*
+	Returns the lookup statistics of all locales of the catalog, for publishing with expvar
+*/
+func (c *Catalog) StatsVar() StatsVar {
+	return StatsVar{c}
+}
+
+// passes a failed lookup to the miss handler, if one is set
+func (c *Catalog) miss(l Locale, val string, err error) (string, error) {
+	handler := c.missHandler.Load().(MissHandler)
+
+	var notFound *types.NotFoundError
+	if handler != nil && errors.As(err, &notFound) {
+		return handler(l, notFound)
+	}
+
+	return val, err
+}
@@ -0,0 +1,109 @@
+package ginta
+
+import (
+	"context"
+	types "github.com/beatgammit/ginta/common"
+	"strings"
+)
+
+/*
+	Returns the name of a language, given by its code, in this locale - for example "Französisch"
+	for Locale("de").DisplayNameOf("fr"). Codes with a script or region are named after their
+	parts, as in "Französisch (Kanada)" for "fr-CA". Applies to the DefaultCatalog.
+
+	Names are looked up in the bundles common.LanguageNamesPrefix, common.RegionNamesPrefix and
+	common.ScriptNamesPrefix, so providers may add names or replace them
+	(internal:languages:fr-CA=Québécois names the full code), and in built-in data for common
+	languages and regions. The locale and its fallback chain are tried in order, each with its
+	provided names first and its built-in names second, so a name in the language of the locale
+	wins over a name in a fallback language. A name of the full code is used unless the language
+	alone can be named in a language tried earlier. Returns the code itself if no name is known.
+*/
+func (l Locale) DisplayNameOf(code string) string {
+	return DefaultCatalog.DisplayNameOf(l, code)
+}
+
+/*
+	Returns the name of a region, given by its ISO 3166 or UN M.49 code, in this locale - for
+	example "Österreich" for Locale("de").RegionNameOf("AT"). See DisplayNameOf
+*/
+func (l Locale) RegionNameOf(region string) string {
+	return DefaultCatalog.RegionNameOf(l, region)
+}
+
+/*
+	Returns the name of a language in a locale of the catalog. See Locale.DisplayNameOf
+*/
+func (c *Catalog) DisplayNameOf(l Locale, code string) string {
+	tag, err := types.ParseTag(code)
+	if err != nil {
+		return code
+	}
+
+	full, fullRank := c.rankedName(l, types.LanguageNamesPrefix, tag.String())
+	name, rank := c.rankedName(l, types.LanguageNamesPrefix, tag.Language)
+	if fullRank >= 0 && (rank < 0 || fullRank <= rank) {
+		return full
+	}
+	if rank < 0 {
+		name = tag.Language
+	}
+
+	qualifiers := []string{}
+	if tag.Script != "" {
+		script, ok := c.name(l, types.ScriptNamesPrefix, tag.Script)
+		if !ok {
+			script = tag.Script
+		}
+		qualifiers = append(qualifiers, script)
+	}
+	if tag.Region != "" {
+		qualifiers = append(qualifiers, c.RegionNameOf(l, tag.Region))
+	}
+	qualifiers = append(qualifiers, tag.Variants...)
+
+	if len(qualifiers) > 0 {
+		name += " (" + strings.Join(qualifiers, ", ") + ")"
+	}
+
+	return name
+}
+
+/*
+	Returns the name of a region in a locale of the catalog. See Locale.RegionNameOf
+*/
+func (c *Catalog) RegionNameOf(l Locale, region string) string {
+	region = strings.ToUpper(region)
+	if name, ok := c.name(l, types.RegionNamesPrefix, region); ok {
+		return name
+	}
+
+	return region
+}
+
+// looks up a name in a bundle of the locale or its fallbacks. See rankedName
+func (c *Catalog) name(l Locale, prefix, key string) (string, bool) {
+	name, rank := c.rankedName(l, prefix, key)
+	return name, rank >= 0
+}
+
+// looks up a name in a bundle of the locale, then in its built-in names, and so on along its
+// fallback chain. Returns the position in the chain the name was found at, zero for the locale
+// itself, and -1 if no locale knows the name
+func (c *Catalog) rankedName(l Locale, prefix, key string) (string, int) {
+	full := prefix + types.ResourceKeySegmentSeparator + key
+	locale, chain, _ := c.activate(context.Background(), l, keyScopes(full, false)...)
+
+	for i, code := range append([]string{locale}, chain...) {
+		// traced rather than requested, as a missing name is no miss of the application
+		if trace := c.universe.Trace(code, full, false); trace.Found {
+			return trace.Value, i
+		}
+
+		if name, ok := builtinNames[prefix][code][key]; ok {
+			return name, i
+		}
+	}
+
+	return "", -1
+}
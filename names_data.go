@@ -0,0 +1,66 @@
+package ginta
+
+import (
+	types "github.com/beatgammit/ginta/common"
+)
+
+// codes of the built-in language, region and script names, in the order of the names below
+var (
+	builtinLanguages = []string{"ar", "de", "en", "es", "fr", "he", "hi", "it", "ja", "ko", "nl", "pl", "pt", "ru", "sv", "tr", "zh"}
+	builtinRegions   = []string{"419", "AT", "BR", "CA", "CH", "CN", "DE", "ES", "FR", "GB", "IN", "IT", "JP", "MX", "NL", "PT", "TW", "US"}
+	builtinScripts   = []string{"Arab", "Cyrl", "Hans", "Hant", "Latn"}
+)
+
+// built-in names by the language they are written in
+var (
+	builtinLanguageNames = map[string][]string{
+		"de": {"Arabisch", "Deutsch", "Englisch", "Spanisch", "Französisch", "Hebräisch", "Hindi", "Italienisch", "Japanisch", "Koreanisch", "Niederländisch", "Polnisch", "Portugiesisch", "Russisch", "Schwedisch", "Türkisch", "Chinesisch"},
+		"en": {"Arabic", "German", "English", "Spanish", "French", "Hebrew", "Hindi", "Italian", "Japanese", "Korean", "Dutch", "Polish", "Portuguese", "Russian", "Swedish", "Turkish", "Chinese"},
+		"es": {"árabe", "alemán", "inglés", "español", "francés", "hebreo", "hindi", "italiano", "japonés", "coreano", "neerlandés", "polaco", "portugués", "ruso", "sueco", "turco", "chino"},
+		"fr": {"arabe", "allemand", "anglais", "espagnol", "français", "hébreu", "hindi", "italien", "japonais", "coréen", "néerlandais", "polonais", "portugais", "russe", "suédois", "turc", "chinois"},
+		"it": {"arabo", "tedesco", "inglese", "spagnolo", "francese", "ebraico", "hindi", "italiano", "giapponese", "coreano", "olandese", "polacco", "portoghese", "russo", "svedese", "turco", "cinese"},
+		"nl": {"Arabisch", "Duits", "Engels", "Spaans", "Frans", "Hebreeuws", "Hindi", "Italiaans", "Japans", "Koreaans", "Nederlands", "Pools", "Portugees", "Russisch", "Zweeds", "Turks", "Chinees"},
+		"pt": {"árabe", "alemão", "inglês", "espanhol", "francês", "hebraico", "híndi", "italiano", "japonês", "coreano", "holandês", "polonês", "português", "russo", "sueco", "turco", "chinês"},
+	}
+	builtinRegionNames = map[string][]string{
+		"de": {"Lateinamerika", "Österreich", "Brasilien", "Kanada", "Schweiz", "China", "Deutschland", "Spanien", "Frankreich", "Vereinigtes Königreich", "Indien", "Italien", "Japan", "Mexiko", "Niederlande", "Portugal", "Taiwan", "Vereinigte Staaten"},
+		"en": {"Latin America", "Austria", "Brazil", "Canada", "Switzerland", "China", "Germany", "Spain", "France", "United Kingdom", "India", "Italy", "Japan", "Mexico", "Netherlands", "Portugal", "Taiwan", "United States"},
+		"es": {"Latinoamérica", "Austria", "Brasil", "Canadá", "Suiza", "China", "Alemania", "España", "Francia", "Reino Unido", "India", "Italia", "Japón", "México", "Países Bajos", "Portugal", "Taiwán", "Estados Unidos"},
+		"fr": {"Amérique latine", "Autriche", "Brésil", "Canada", "Suisse", "Chine", "Allemagne", "Espagne", "France", "Royaume-Uni", "Inde", "Italie", "Japon", "Mexique", "Pays-Bas", "Portugal", "Taïwan", "États-Unis"},
+		"it": {"America Latina", "Austria", "Brasile", "Canada", "Svizzera", "Cina", "Germania", "Spagna", "Francia", "Regno Unito", "India", "Italia", "Giappone", "Messico", "Paesi Bassi", "Portogallo", "Taiwan", "Stati Uniti"},
+		"nl": {"Latijns-Amerika", "Oostenrijk", "Brazilië", "Canada", "Zwitserland", "China", "Duitsland", "Spanje", "Frankrijk", "Verenigd Koninkrijk", "India", "Italië", "Japan", "Mexico", "Nederland", "Portugal", "Taiwan", "Verenigde Staten"},
+		"pt": {"América Latina", "Áustria", "Brasil", "Canadá", "Suíça", "China", "Alemanha", "Espanha", "França", "Reino Unido", "Índia", "Itália", "Japão", "México", "Países Baixos", "Portugal", "Taiwan", "Estados Unidos"},
+	}
+	builtinScriptNames = map[string][]string{
+		"de": {"Arabisch", "Kyrillisch", "Vereinfacht", "Traditionell", "Lateinisch"},
+		"en": {"Arabic", "Cyrillic", "Simplified", "Traditional", "Latin"},
+		"es": {"árabe", "cirílico", "simplificado", "tradicional", "latino"},
+		"fr": {"arabe", "cyrillique", "simplifié", "traditionnel", "latin"},
+		"it": {"arabo", "cirillico", "semplificato", "tradizionale", "latino"},
+		"nl": {"Arabisch", "Cyrillisch", "vereenvoudigd", "traditioneel", "Latijns"},
+		"pt": {"árabe", "cirílico", "simplificado", "tradicional", "latino"},
+	}
+)
+
+// built-in names by bundle prefix, language written in and code
+var builtinNames = map[string]map[string]map[string]string{
+	types.LanguageNamesPrefix: indexNames(builtinLanguages, builtinLanguageNames),
+	types.RegionNamesPrefix:   indexNames(builtinRegions, builtinRegionNames),
+	types.ScriptNamesPrefix:   indexNames(builtinScripts, builtinScriptNames),
+}
+
+func indexNames(codes []string, names map[string][]string) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for language, translated := range names {
+		if len(translated) != len(codes) {
+			panic("ginta: built-in names of " + language + " do not match their codes")
+		}
+
+		result[language] = make(map[string]string)
+		for i, code := range codes {
+			result[language][code] = translated[i]
+		}
+	}
+
+	return result
+}
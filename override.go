@@ -0,0 +1,81 @@
+package ginta
+
+/*
+	Overrides a resource of this locale at runtime, for example from an admin console.
+	Overrides are kept in a separate layer on top of the resources of all providers: they
+	take precedence over every provider, and survive reloads (See Reload). Overriding with an
+	empty value sets the resource to the empty string, DeleteOverride removes the override.
+	Applies to the DefaultCatalog.
+
+	Overrides of a locale no provider offers are kept, and take effect once a provider for
+	the locale is registered.
+*/
+func (l Locale) SetOverride(key, value string) {
+	DefaultCatalog.SetOverride(l, key, value)
+}
+
+/*
+	Removes an override of this locale, making the resource of the providers visible again
+*/
+func (l Locale) DeleteOverride(key string) {
+	DefaultCatalog.DeleteOverride(l, key)
+}
+
+/*
+	Sets several overrides of this locale at once, and removes the overrides of the deleted
+	keys. Deletions are applied first, so keys both set and deleted end up set. Lookups see
+	either none or all of the changes.
+*/
+func (l Locale) ApplyOverrides(set map[string]string, deleted ...string) {
+	DefaultCatalog.ApplyOverrides(l, set, deleted...)
+}
+
+/*
+	Removes all overrides of this locale
+*/
+func (l Locale) ClearOverrides() {
+	DefaultCatalog.ClearOverrides(l)
+}
+
+/*
+	Returns a copy of the overrides of this locale, for example to persist them. The result
+	can be passed to ApplyOverrides to restore them.
+*/
+func (l Locale) Overrides() map[string]string {
+	return DefaultCatalog.Overrides(l)
+}
+
+/*
+	Overrides a resource of a locale within the catalog. See Locale.SetOverride
+*/
+func (c *Catalog) SetOverride(l Locale, key, value string) {
+	c.ApplyOverrides(l, map[string]string{key: value})
+}
+
+/*
+	Removes an override of a locale within the catalog. See Locale.DeleteOverride
+*/
+func (c *Catalog) DeleteOverride(l Locale, key string) {
+	c.ApplyOverrides(l, nil, key)
+}
+
+/*
+	Sets several overrides of a locale within the catalog at once. See Locale.ApplyOverrides
+*/
+func (c *Catalog) ApplyOverrides(l Locale, set map[string]string, deleted ...string) {
+	c.universe.ApplyOverrides(string(l.Canonical()), set, deleted...)
+}
+
+/*
+	Removes all overrides of a locale within the catalog
+*/
+func (c *Catalog) ClearOverrides(l Locale) {
+	c.universe.ClearOverrides(string(l.Canonical()))
+}
+
+/*
+	Returns a copy of the overrides of a locale within the catalog. See Locale.Overrides
+*/
+func (c *Catalog) Overrides(l Locale) map[string]string {
+	return c.universe.Overrides(string(l.Canonical()))
+}
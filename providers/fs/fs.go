@@ -20,7 +20,14 @@ they still can put hierarchical keys into a file)
 
 Its bootstrap mechanism is achieved by a file named "bootstrap.txt", 
 which is loaded when the language is first discovered, and must contain
-the 
+the display name of the language (internal:DisplayName). It may describe
+the language further (See common.Language.SetProperty):
+
+	internal:DisplayName=German
+	internal:NativeName=Deutsch
+	internal:Script=Latn
+	internal:PluralRules=one_other
+	internal:FirstDayOfWeek=monday
 */
 package fs
 
@@ -31,13 +38,15 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type provider string
 
-// Constructs a new provider, with the root directory pointing to the specified path
+// Constructs a new provider, with the root directory pointing to the specified path. Every
+// directory is a bundle, loaded once a lookup needs it (See ginta.BundleLister)
 func New(path string) ginta.LanguageProvider {
-	return &multi.Provider{provider(path), provider(path)}
+	return &multi.BundleProvider{multi.Provider{provider(path), provider(path)}}
 }
 
 const (
@@ -48,69 +57,129 @@ const (
 )
 
 func (f provider) Enumerate() <-chan types.Language {
+	return f.EnumerateReporting(nil)
+}
+
+// Enumerates the languages, reporting an unreadable root directory and malformed bootstrap files
+func (f provider) EnumerateReporting(report func(error)) <-chan types.Language {
 	c := make(chan types.Language)
 
-	go enumerate(string(f), c)
+	go enumerate(string(f), c, report)
 
 	return c
 }
 
-func enumerate(baseDir string, target chan<- types.Language) {
+// Describes the provider by its root directory
+func (f provider) String() string {
+	return "fs:" + string(f)
+}
+
+func enumerate(baseDir string, target chan<- types.Language, report func(error)) {
 	defer close(target)
 
 	if entries, err := ioutil.ReadDir(baseDir); err == nil {
 		for _, entry := range entries {
 			if entry.IsDir() {
-				displayName, err := parseBootstrap(baseDir + "/" + entry.Name())
-
-				if err != nil {
-					displayName = entry.Name()
-				}
-				target <- types.Language{entry.Name(), displayName}
+				target <- parseBootstrap(baseDir+"/"+entry.Name(), entry.Name(), report)
 			}
 		}
+	} else if report != nil {
+		report(&types.LoadError{File: baseDir, Err: err})
 	}
 }
 
-func parseBootstrap(dir string) (string, error) {
-	c := make(chan types.Resource)
+// describes a language by the internal: resources of its bootstrap file. The display name
+// defaults to the code
+func parseBootstrap(dir, code string, report func(error)) types.Language {
+	c := make(chan types.LocatedResource)
 	go func() {
-		if file, err := open(dir + bootstrapExtension); err == nil {
-			multi.ParseTo(file, "", c)
+		path := dir + bootstrapExtension
+		if file, err := open(path); err == nil {
+			multi.ParseLocated(file, path, "", c, report)
 		}
 
 		close(c)
 	}()
 
+	lang := types.Language{Code: code}
 	for res := range c {
-		if res.Key == types.DisplayNameResourceKey {
-			return res.Value, nil
+		if _, err := lang.SetProperty(res.Key, res.Value); err != nil && report != nil {
+			report(&types.LoadError{File: res.Origin.File, Line: res.Origin.Line, Err: err})
 		}
 	}
 
-	return "", types.ResourceNotFoundError(types.DisplayNameResourceKey)
+	if lang.DisplayName == "" {
+		lang.DisplayName = code
+	}
+
+	return lang
 }
 
 func (f provider) Walk(code string) <-chan multi.ResourceSource {
 	c := make(chan multi.ResourceSource)
 	go func() {
 		defer close(c)
-		list(string(f)+"/"+code, "", c)
+		list(string(f)+"/"+code, "", true, c)
 	}()
 
 	return c
 }
 
-func list(dirPath string, prefix string, target chan<- multi.ResourceSource) {
+// Lists the prefixes of all directories of a language, the root directory being the empty prefix
+func (f provider) Bundles(code string) []string {
+	return bundles(string(f)+"/"+code, "", []string{})
+}
+
+// Walks the files of the directory of a bundle, but not its subdirectories
+func (f provider) WalkBundle(code, prefix string) <-chan multi.ResourceSource {
+	c := make(chan multi.ResourceSource)
+	go func() {
+		defer close(c)
+
+		dirPath := string(f) + "/" + code
+		if prefix != "" {
+			dirPath += "/" + strings.Replace(prefix, types.ResourceKeySegmentSeparator, "/", -1)
+			prefix += types.ResourceKeySegmentSeparator
+		}
+		list(dirPath, prefix, false, c)
+	}()
+
+	return c
+}
+
+func bundles(dirPath, prefix string, found []string) []string {
+	found = append(found, prefix)
+	if entries, err := ioutil.ReadDir(filepath.FromSlash(dirPath)); err == nil {
+		for _, file := range entries {
+			if file.IsDir() {
+				sub := file.Name()
+				if prefix != "" {
+					sub = prefix + types.ResourceKeySegmentSeparator + sub
+				}
+				found = bundles(dirPath+"/"+file.Name(), sub, found)
+			}
+		}
+	}
+
+	return found
+}
+
+func list(dirPath string, prefix string, recurse bool, target chan<- multi.ResourceSource) {
 	if entries, err := ioutil.ReadDir(filepath.FromSlash(dirPath)); err == nil {
 		for _, file := range entries {
 			name := dirPath + "/" + file.Name()
 			if file.IsDir() {
-				list(name, prefix+file.Name()+types.ResourceKeySegmentSeparator, target)
+				if recurse {
+					list(name, prefix+file.Name()+types.ResourceKeySegmentSeparator, recurse, target)
+				}
 			} else if file, err := open(name); err == nil {
-				target <- multi.ResourceSource{file, prefix}
+				target <- multi.ResourceSource{file, prefix, name, nil}
+			} else {
+				target <- multi.ResourceSource{nil, prefix, name, err}
 			}
 		}
+	} else {
+		target <- multi.ResourceSource{nil, prefix, dirPath, err}
 	}
 }
 
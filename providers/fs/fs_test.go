@@ -3,6 +3,7 @@ package fs
 import (
 	"bytes"
 	"github.com/beatgammit/ginta"
+	"github.com/beatgammit/ginta/common"
 	"io"
 	"io/ioutil"
 	"os"
@@ -148,3 +149,258 @@ func TestDescendDirTree(t *testing.T) {
 		}
 	}
 }
+
+func TestReportUnreadableFile(t *testing.T) {
+	dir := prepare("t4", t)
+	defer scrub(dir, t)
+
+	if err := os.Symlink(dir+"/missing.txt", dir+path1+"/dangling.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	if err := c.Register(New(dir)); err != nil {
+		t.Error(err)
+	}
+
+	if str, err := c.GetResource("en", "test:err_file_not_found"); err != nil || str != "Its gone!" {
+		t.Error(str, err)
+	}
+
+	if origin, ok := c.Origin("en", "test:err_no_space_left"); !ok || origin != (common.Origin{"fs:" + dir, dir + file2, 2}) {
+		t.Error(origin, ok)
+	}
+
+	errs := c.Errors("en")
+	if len(errs) != 1 {
+		t.Fatal(errs)
+	}
+
+	if loadErr, ok := errs[0].(*common.LoadError); !ok || loadErr.File != dir+path1+"/dangling.txt" || loadErr.Code != "en" || loadErr.Provider != "fs:"+dir {
+		t.Error(errs[0])
+	}
+
+	c.SetStrict(true)
+	if str, err := c.GetResource("en", "test:err_file_not_found"); err == nil {
+		t.Error(str)
+	}
+}
+
+func TestReportMissingRoot(t *testing.T) {
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	err := c.Register(New("/does/not/exist"))
+	if errs, ok := err.(common.LoadErrors); !ok || len(errs) != 1 {
+		t.Error(err)
+	}
+}
+
+func TestReportConflicts(t *testing.T) {
+	dir := prepare("t5", t)
+	defer scrub(dir, t)
+
+	file := dir + path1 + "/errors3.txt"
+	if err := dumpFile(file, "\nerr_file_not_found=Vanished\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	logged := make(chan common.Conflict, 1)
+	c.SetConflictHandler(func(conflict common.Conflict) {
+		logged <- conflict
+	})
+	c.Register(New(dir))
+
+	if str, err := c.GetResource("en", "test:err_file_not_found"); err != nil || str != "Vanished" {
+		t.Error(str, err)
+	}
+
+	conflicts := c.Conflicts("en")
+	if len(conflicts) != 1 {
+		t.Fatal(conflicts)
+	}
+
+	expect := common.Conflict{"en", "test:err_file_not_found",
+		common.Origin{"fs:" + dir, file, 2},
+		common.Origin{"fs:" + dir, dir + file2, 1}}
+	if conflicts[0] != expect {
+		t.Error(conflicts[0])
+	}
+
+	if conflict := <-logged; conflict != expect {
+		t.Error(conflict)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	dir := prepare("t6", t)
+	defer scrub(dir, t)
+
+	if err := dumpFile(dir+path1+"/labels.txt", "#. Shown on the save button\n#, max-length=4\nsave=Save it\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ginta.NewCatalog()
+	defer c.Close()
+	c.Register(New(dir))
+
+	if meta, ok := c.Metadata("en", "test:save"); !ok || meta.Description != "Shown on the save button" || meta.MaxLength != 4 {
+		t.Error(meta, ok)
+	}
+
+	if _, ok := c.Metadata("en", "test:err_file_not_found"); ok {
+		t.Error("metadata for a resource without metadata comments")
+	}
+
+	exported := c.Export("en", "test")
+	if len(exported) != 4 || exported[3].Key != "test:save" || exported[3].Origin.Line != 3 {
+		t.Fatal(exported)
+	}
+
+	if err := exported[3].Metadata.Check(exported[3].Value); err != common.ErrTooLong {
+		t.Error(err)
+	}
+}
+
+func TestLanguageProperties(t *testing.T) {
+	dir := prepare("t7", t)
+	defer scrub(dir, t)
+
+	if err := os.MkdirAll(dir+"/ar", dirPermissions); err != nil {
+		t.Fatal(err)
+	}
+	bootstrap := "internal:DisplayName=Arabic\ninternal:NativeName=العربية\ninternal:Script=Arab\n" +
+		"internal:Parent=en\ninternal:PluralRules=zero_one_two_few_many_other\ninternal:FirstDayOfWeek=Saturday\n" +
+		"internal:Direction=sideways\n"
+	if err := dumpFile(dir+"/ar"+bootstrapExtension, bootstrap); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	err := c.Register(New(dir))
+	if errs, ok := err.(common.LoadErrors); !ok || len(errs) != 1 || errs[0].(*common.LoadError).Line != 7 {
+		t.Error(err)
+	}
+
+	var lang *common.Language
+	for _, l := range c.List() {
+		if l.Code == "ar" {
+			lang = l
+		}
+	}
+
+	expect := common.Language{"ar", "Arabic", "العربية", common.RightToLeft, "Arab", "en", "zero_one_two_few_many_other", common.Saturday}
+	if lang == nil || *lang != expect {
+		t.Fatal(lang)
+	}
+
+	if en, ok := c.Language("en"); !ok || en.DisplayName != "English" || en.Direction != common.UnknownDirection {
+		t.Error(en, ok)
+	}
+}
+
+func TestLoadByDirectory(t *testing.T) {
+	dir := prepare("t8", t)
+	defer scrub(dir, t)
+
+	if err := os.Symlink(dir+"/missing.txt", dir+path2+"/dangling.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ginta.NewCatalog()
+	defer c.Close()
+	c.Register(New(dir))
+
+	// the directory of a/longer/path is not read for resources of test
+	if str, err := c.GetResource("en", "test:err_file_not_found"); err != nil || str != "Its gone!" || len(c.Errors("en")) != 0 {
+		t.Error(str, err, c.Errors("en"))
+	}
+
+	if str, err := c.GetResource("en", "a:longer:path:greeting"); err != nil || str != "Hello World" || len(c.Errors("en")) != 1 {
+		t.Error(str, err, c.Errors("en"))
+	}
+
+	if keys := c.Keys("en", "a"); len(keys) != 1 || keys[0] != "a:longer:path:greeting" {
+		t.Error(keys)
+	}
+}
+
+func TestReloadNewDirectory(t *testing.T) {
+	dir := prepare("t9", t)
+	defer scrub(dir, t)
+
+	c := ginta.NewCatalog()
+	defer c.Close()
+	c.Register(New(dir))
+
+	if str, err := c.GetResource("en", "test:err_file_not_found"); err != nil || str != "Its gone!" {
+		t.Error(str, err)
+	}
+
+	if err := os.MkdirAll(dir+language+"/b", dirPermissions); err != nil {
+		t.Fatal(err)
+	}
+	if err := dumpFile(dir+language+"/b/g.txt", "y=added\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(dir + path1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Reload("en"); err != nil {
+		t.Error(err)
+	}
+
+	if str, err := c.GetResource("en", "b:y"); err != nil || str != "added" {
+		t.Error(str, err)
+	}
+	if str, err := c.GetResource("en", "test:err_file_not_found"); err == nil {
+		t.Error(str)
+	}
+}
+
+func TestDeeperBundleWins(t *testing.T) {
+	dir := prepare("t10", t)
+	defer scrub(dir, t)
+
+	if err := os.MkdirAll(dir+language+"/menu", dirPermissions); err != nil {
+		t.Fatal(err)
+	}
+	if err := dumpFile(dir+language+"/z.txt", "menu:x=root\ntitle=Title\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dumpFile(dir+language+"/menu/f.txt", "x=dir\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ginta.NewCatalog()
+	defer c.Close()
+	c.Register(New(dir))
+
+	// the root bundle is loaded on its own first
+	if str, err := c.GetResource("en", "title"); err != nil || str != "Title" {
+		t.Error(str, err)
+	}
+	if str, err := c.GetResource("en", "menu:x"); err != nil || str != "dir" {
+		t.Error(str, err)
+	}
+
+	if conflicts := c.Conflicts("en"); len(conflicts) != 1 || conflicts[0].Winner.File != dir+language+"/menu/f.txt" {
+		t.Error(conflicts)
+	}
+
+	// and along with the bundle on reload
+	if err := c.Reload("en"); err != nil {
+		t.Error(err)
+	}
+	if str, err := c.GetResource("en", "menu:x"); err != nil || str != "dir" {
+		t.Error(str, err)
+	}
+}
@@ -8,21 +8,36 @@ package multisrc
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"github.com/beatgammit/ginta"
 	"github.com/beatgammit/ginta/common"
 	"io"
+	"strconv"
 	"strings"
 )
 
 const trim = " \t\r\n"
 
+var (
+	// A line has a key, but no = separator
+	ErrMissingSeparator = errors.New("missing key-value separator '='")
+	// A line has a value, but no key
+	ErrMissingKey = errors.New("missing resource key")
+	// A metadata comment has an unknown flag, or a malformed max-length
+	ErrBadMetadata = errors.New("malformed metadata comment")
+)
+
 /*
 A resource source is defined by being able to be read, and an info about a
-common prefix to all resources encountered
+common prefix to all resources encountered. The name (e.g. a file name) is
+used in error reports. Walkers report sources that cannot be read by setting
+Err (and leaving Reader nil).
 */
 type ResourceSource struct {
 	Reader io.ReadCloser
 	Prefix string
+	Name   string
+	Err    error
 }
 
 /*
@@ -34,6 +49,17 @@ type Walker interface {
 	Walk(code string) <-chan ResourceSource
 }
 
+/*
+A walker that can also enumerate the bundles of a language (by their prefix, the
+empty prefix standing for the root bundle), and walk the sources of a single bundle.
+The sources of a bundle must only define keys within its prefix
+*/
+type BundleWalker interface {
+	Walker
+	Bundles(code string) []string
+	WalkBundle(code, prefix string) <-chan ResourceSource
+}
+
 // Convenience walker for simple functions
 type WalkerFunc func(string) <-chan ResourceSource
 
@@ -52,18 +78,97 @@ func (p *Provider) Enumerate() <-chan common.Language {
 	return p.Enumerator.Enumerate()
 }
 
+// Enumerates, reporting failures if the Enumerator supports it
+func (p *Provider) EnumerateReporting(report func(error)) <-chan common.Language {
+	if e, ok := p.Enumerator.(ginta.ReportingEnumerator); ok {
+		return e.EnumerateReporting(report)
+	}
+
+	return p.Enumerator.Enumerate()
+}
+
 // imports each resource in the providers Walker,  and calls ParseTo
 // for each resource file found
 func (p *Provider) List(code string) <-chan common.Resource {
+	return p.ListReporting(code, nil)
+}
+
+// Like List, but reports sources that failed to be read, and malformed lines
+func (p *Provider) ListReporting(code string, report func(error)) <-chan common.Resource {
 	c := make(chan common.Resource)
-	go list(p.Walker.Walk(code), c)
+	go func() {
+		defer close(c)
+		for res := range p.ListLocated(code, report) {
+			c <- res.Resource
+		}
+	}()
+
 	return c
 }
 
-func list(in <-chan ResourceSource, target chan<- common.Resource) {
+// Like ListReporting, but states the source name and line each resource is defined at
+func (p *Provider) ListLocated(code string, report func(error)) <-chan common.LocatedResource {
+	c := make(chan common.LocatedResource)
+	go list(p.Walker.Walk(code), c, report)
+	return c
+}
+
+/*
+A provider whose resources are loaded by bundle (See ginta.BundleLister), if its
+Walker is a BundleWalker. Otherwise, all resources form a single root bundle
+*/
+type BundleProvider struct {
+	Provider
+}
+
+// Lists the bundles of the Walker
+func (p *BundleProvider) Bundles(code string, report func(error)) []string {
+	if w, ok := p.Walker.(BundleWalker); ok {
+		return w.Bundles(code)
+	}
+
+	return []string{""}
+}
+
+// Like ListLocated, but for the sources of a single bundle
+func (p *BundleProvider) ListBundle(code, prefix string, report func(error)) <-chan common.LocatedResource {
+	c := make(chan common.LocatedResource)
+	if w, ok := p.Walker.(BundleWalker); ok {
+		go list(w.WalkBundle(code, prefix), c, report)
+	} else if prefix == "" {
+		go list(p.Walker.Walk(code), c, report)
+	} else {
+		close(c)
+	}
+
+	return c
+}
+
+// Describes the provider by its Walker, if that implements fmt.Stringer
+func (p *Provider) String() string {
+	if s, ok := p.Walker.(interface {
+		String() string
+	}); ok {
+		return s.String()
+	}
+
+	return "multisrc"
+}
+
+func list(in <-chan ResourceSource, target chan<- common.LocatedResource, report func(error)) {
 	defer close(target)
 	for input := range in {
-		ParseTo(input.Reader, input.Prefix, target)
+		if input.Err != nil {
+			reportTo(report, &common.LoadError{File: input.Name, Err: input.Err})
+		} else {
+			ParseLocated(input.Reader, input.Name, input.Prefix, target, report)
+		}
+	}
+}
+
+func reportTo(report func(error), err error) {
+	if report != nil {
+		report(err)
 	}
 }
 
@@ -87,17 +192,82 @@ is interpreted the following way:
 
 Lines are terminated by newlines (0x0a). Resource key names are separated from their values
 by = characters. Keys may not contain additional equals characters, but values may.
+
+Comments at the start of a line may carry metadata for the resource that follows them
+(See common.Metadata), and are otherwise ignored:
+	#. a description of the resource for translators, may span several comments
+	#, flags, separated by commas: fuzzy, approved and max-length=<characters>
+
+An empty line discards the metadata collected so far. Example:
+	#. Label of the button that saves the document
+	#, approved, max-length=12
+	save=Save
 */
 func ParseTo(inRaw io.ReadCloser, prefix string, target chan<- common.Resource) {
+	Parse(inRaw, "", prefix, target, nil)
+}
+
+/*
+Like ParseTo, but reports malformed lines (a key without =, or a value without key) and read errors
+to the report function, as *common.LoadError carrying the name of the input and the line number.
+The report function may be nil.
+*/
+func Parse(inRaw io.ReadCloser, name, prefix string, target chan<- common.Resource, report func(error)) {
+	parse(inRaw, name, prefix, func(res common.LocatedResource) {
+		target <- res.Resource
+	}, report)
+}
+
+/*
+Like Parse, but states the name of the input, and the line each resource starts at
+*/
+func ParseLocated(inRaw io.ReadCloser, name, prefix string, target chan<- common.LocatedResource, report func(error)) {
+	parse(inRaw, name, prefix, func(res common.LocatedResource) {
+		target <- res
+	}, report)
+}
+
+func parse(inRaw io.ReadCloser, name, prefix string, emit func(common.LocatedResource), report func(error)) {
 	defer inRaw.Close()
 	in := bufio.NewReader(inRaw)
 
-	var key, val bytes.Buffer
+	var key, val, comment bytes.Buffer
 	var err error
 	var nextRune rune
+	var buffer runeWriter = &key
+
+	// metadata collected for the next resource
+	var metadata *common.Metadata
+	attach := func(res common.LocatedResource) {
+		res.Metadata, metadata = metadata, nil
+		emit(res)
+	}
+
+	line, lineStart := 1, 1
+	separated := false
+	transmit := func() {
+		switch {
+		case buffer == &comment:
+			var metaErr error
+			if metadata, metaErr = parseMetadata(metadata, comment.String()); metaErr != nil {
+				reportTo(report, &common.LoadError{File: name, Line: lineStart, Err: metaErr})
+			}
+			comment.Reset()
+		case buffer == &key && strings.Trim(key.String(), trim) == "":
+			metadata = nil
+		}
+
+		origin := common.Origin{File: name, Line: lineStart}
+		if lineErr := transmitValid(prefix, &key, &val, separated, origin, attach); lineErr != nil {
+			reportTo(report, &common.LoadError{File: name, Line: lineStart, Err: lineErr})
+		}
+		key.Reset()
+		val.Reset()
+		separated = false
+		lineStart = line
+	}
 
 	// all possible targets never fail at a write, so checking writes is not necessary
-	var buffer runeWriter = &key
 	backslash := false
 	for nextRune, _, err = in.ReadRune(); err == nil; nextRune, _, err = in.ReadRune() {
 		if backslash {
@@ -107,6 +277,7 @@ func ParseTo(inRaw io.ReadCloser, prefix string, target chan<- common.Resource)
 				nextRune = '\n'
 			// this allows the trailing backslash to join lines 
 			case '\n':
+				line++
 				continue
 			default:
 				buffer.WriteRune('\\')
@@ -117,17 +288,24 @@ func ParseTo(inRaw io.ReadCloser, prefix string, target chan<- common.Resource)
 				backslash = true
 				continue
 			case '#':
-				buffer = runeDrop(0)
+				if buffer == &key && strings.Trim(key.String(), trim) == "" {
+					buffer = &comment
+				} else if buffer != &comment {
+					buffer = runeDrop(0)
+				}
 				continue
 			case '=':
+				if buffer == &comment {
+					break
+				}
 				if buffer == &key {
 					buffer = &val
+					separated = true
 				}
 				continue
 			case '\n':
-				transmitValid(prefix, &key, &val, target)
-				key.Reset()
-				val.Reset()
+				line++
+				transmit()
 				buffer = &key
 				continue
 			}
@@ -137,16 +315,65 @@ func ParseTo(inRaw io.ReadCloser, prefix string, target chan<- common.Resource)
 	}
 
 	if err == io.EOF {
-		transmitValid(prefix, &key, &val, target)
+		transmit()
+	} else {
+		reportTo(report, &common.LoadError{File: name, Line: line, Err: err})
 	}
 }
 
-func transmitValid(prefix string, key, val *bytes.Buffer, target chan<- common.Resource) {
+// adds the metadata of a comment at the start of a line to the metadata collected so far
+func parseMetadata(metadata *common.Metadata, comment string) (*common.Metadata, error) {
+	if comment == "" || (comment[0] != '.' && comment[0] != ',') {
+		return metadata, nil
+	}
+
+	if metadata == nil {
+		metadata = new(common.Metadata)
+	}
+
+	text := strings.Trim(comment[1:], trim)
+	if comment[0] == '.' {
+		if metadata.Description != "" {
+			metadata.Description += "\n"
+		}
+		metadata.Description += text
+		return metadata, nil
+	}
+
+	for _, flag := range strings.Split(text, ",") {
+		flag = strings.Trim(flag, trim)
+		switch {
+		case flag == "fuzzy":
+			metadata.Fuzzy = true
+		case flag == "approved":
+			metadata.Approved = true
+		case strings.HasPrefix(flag, "max-length="):
+			length, err := strconv.Atoi(flag[len("max-length="):])
+			if err != nil || length < 0 {
+				return metadata, ErrBadMetadata
+			}
+			metadata.MaxLength = length
+		case flag != "":
+			return metadata, ErrBadMetadata
+		}
+	}
+
+	return metadata, nil
+}
+
+func transmitValid(prefix string, key, val *bytes.Buffer, separated bool, origin common.Origin, emit func(common.LocatedResource)) error {
 
 	keyStr := strings.Trim(key.String(), trim)
 	valStr := strings.Trim(val.String(), trim)
 
-	if keyStr != "" && valStr != "" {
-		target <- common.Resource{prefix + keyStr, valStr}
+	switch {
+	case keyStr != "" && valStr != "":
+		emit(common.LocatedResource{common.Resource{prefix + keyStr, valStr}, origin, nil})
+	case keyStr != "" && !separated:
+		return ErrMissingSeparator
+	case keyStr == "" && valStr != "":
+		return ErrMissingKey
 	}
+
+	return nil
 }
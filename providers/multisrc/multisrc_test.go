@@ -1,6 +1,7 @@
 package multisrc
 
 import (
+	"errors"
 	"bytes"
 	"github.com/beatgammit/ginta/common"
 	"io"
@@ -142,7 +143,7 @@ func TestLineTermination(t *testing.T) {
 }
 
 func TestScanPipeline(t *testing.T) {
-	out := make(chan common.Resource)
+	out := make(chan common.LocatedResource)
 	in := make(chan ResourceSource)
 	buffers := []io.ReadCloser{
 		ioutil.NopCloser(bytes.NewBuffer([]byte(simpleContent))),
@@ -153,13 +154,13 @@ func TestScanPipeline(t *testing.T) {
 
 	go func() {
 		for _, buffer := range buffers {
-			in <- ResourceSource{buffer, ""}
+			in <- ResourceSource{Reader: buffer}
 		}
 
 		close(in)
 	}()
 
-	go list(in, out)
+	go list(in, out, nil)
 
 	res := <-out
 
@@ -201,3 +202,132 @@ func TestScanPipeline(t *testing.T) {
 		t.Error("channel should be closed after last reader")
 	}
 }
+
+func TestParseReportsMalformedLines(t *testing.T) {
+	buff := ioutil.NopCloser(bytes.NewBuffer([]byte("k1=v1\nbroken\n=value\nlong=joined\\\nline\n# just a comment\nnovalue=\nk2 # no separator\n")))
+	c := make(chan common.Resource)
+	errs := []error{}
+
+	go func() {
+		defer close(c)
+		Parse(buff, "test.txt", "", c, func(err error) {
+			errs = append(errs, err)
+		})
+	}()
+
+	count := 0
+	for _ = range c {
+		count++
+	}
+
+	if count != 2 {
+		t.Error(count)
+	}
+
+	expect := []struct {
+		line int
+		err  error
+	}{{2, ErrMissingSeparator}, {3, ErrMissingKey}, {8, ErrMissingSeparator}}
+
+	if len(errs) != len(expect) {
+		t.Fatal(errs)
+	}
+
+	for i, e := range expect {
+		loadErr, ok := errs[i].(*common.LoadError)
+		if !ok || loadErr.File != "test.txt" || loadErr.Line != e.line || loadErr.Err != e.err {
+			t.Error(errs[i])
+		}
+	}
+}
+
+func TestListReportsUnreadableSources(t *testing.T) {
+	failure := errors.New("unreadable")
+	p := &Provider{nil, WalkerFunc(func(_ string) <-chan ResourceSource {
+		in := make(chan ResourceSource, 2)
+		in <- ResourceSource{Name: "gone.txt", Err: failure}
+		in <- ResourceSource{Reader: ioutil.NopCloser(bytes.NewBufferString("x=y\n")), Name: "ok.txt"}
+		close(in)
+		return in
+	})}
+
+	errs := []error{}
+	count := 0
+	for _ = range p.ListReporting("en", func(err error) { errs = append(errs, err) }) {
+		count++
+	}
+
+	if count != 1 || len(errs) != 1 {
+		t.Fatal(count, errs)
+	}
+
+	if loadErr, ok := errs[0].(*common.LoadError); !ok || loadErr.File != "gone.txt" || loadErr.Err != failure {
+		t.Error(errs[0])
+	}
+}
+
+func TestListLocated(t *testing.T) {
+	p := &Provider{nil, WalkerFunc(func(_ string) <-chan ResourceSource {
+		in := make(chan ResourceSource, 2)
+		in <- ResourceSource{Reader: ioutil.NopCloser(bytes.NewBufferString(commentLines)), Name: "a.txt"}
+		in <- ResourceSource{Reader: ioutil.NopCloser(bytes.NewBufferString("x=y\n" + lineTermination + "\nz=z")), Prefix: "p:", Name: "b.txt"}
+		close(in)
+		return in
+	})}
+
+	expect := []struct {
+		key, file string
+		line      int
+	}{{"k1", "a.txt", 2}, {"p:x", "b.txt", 1}, {"p:long", "b.txt", 2}, {"p:z", "b.txt", 4}}
+
+	i := 0
+	for res := range p.ListLocated("en", nil) {
+		if i >= len(expect) {
+			t.Fatal("unexpected", res)
+		}
+		if e := expect[i]; res.Key != e.key || res.Origin.File != e.file || res.Origin.Line != e.line {
+			t.Error(res)
+		}
+		i++
+	}
+
+	if i != len(expect) {
+		t.Error(i)
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	content := "#. Label of the save button\n#.  in the toolbar\n# not metadata\n#, approved, max-length=12\nsave=Save # inline\n" +
+		"#, fuzzy\n\nplain=Plain\n#, fuzzy, bogus\nopen=Open\n"
+	c := make(chan common.LocatedResource)
+	errs := []error{}
+
+	go func() {
+		defer close(c)
+		ParseLocated(ioutil.NopCloser(bytes.NewBufferString(content)), "test.txt", "", c, func(err error) {
+			errs = append(errs, err)
+		})
+	}()
+
+	res := <-c
+	expect := common.Metadata{"Label of the save button\nin the toolbar", 12, false, true}
+	if res.Key != "save" || res.Value != "Save" || res.Metadata == nil || *res.Metadata != expect {
+		t.Error(res, res.Metadata)
+	}
+
+	if res = <-c; res.Key != "plain" || res.Metadata != nil {
+		t.Error(res, res.Metadata)
+	}
+
+	if res = <-c; res.Key != "open" || res.Metadata == nil || !res.Metadata.Fuzzy {
+		t.Error(res, res.Metadata)
+	}
+
+	if _, ok := <-c; ok {
+		t.Error("too many resources")
+	}
+
+	if len(errs) != 1 || errs[0].(*common.LoadError).Err != ErrBadMetadata || errs[0].(*common.LoadError).Line != 9 {
+		t.Error(errs)
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Pseudo-locales, derived on the fly from a source language of a catalog. Pseudo-locales
+make untranslated (hard-coded) strings, truncated layouts and mirroring bugs visible
+before any translation exists:
+
+	en-XA  accents all letters and expands values by about 40%: [Šàṽé ~~]
+	ar-XB  mirrors values right-to-left, and is declared as a right-to-left language
+
+Placeholders of the fmt package ({0}, {1,number,...}) are kept unchanged, so pseudo
+translated messages can still be compiled. The pseudo-locales are registered like any
+other provider:
+
+	ginta.Register(pseudo.Accented(nil, "en"))
+	ginta.Register(pseudo.Mirrored(nil, "en"))
+
+The resources are derived when the pseudo-locale is activated. Reload the pseudo-locale
+(See ginta.Reload) to pick up later changes of its source.
+*/
+package pseudo
+
+import (
+	"bytes"
+	"github.com/beatgammit/ginta"
+	types "github.com/beatgammit/ginta/common"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Code of the accented and expanded pseudo-locale
+	AccentedCode = "en-XA"
+	// Code of the mirrored pseudo-locale
+	MirroredCode = "ar-XB"
+)
+
+// Unicode right-to-left override and pop directional formatting
+const (
+	rightToLeftOverride = '\u202e'
+	popDirection        = '\u202c'
+)
+
+// accented forms of the ASCII letters
+var accents = map[rune]rune{
+	'a': 'à', 'b': 'ƀ', 'c': 'ç', 'd': 'ð', 'e': 'é', 'f': 'ƒ', 'g': 'ĝ', 'h': 'ĥ', 'i': 'î',
+	'j': 'ĵ', 'k': 'ķ', 'l': 'ļ', 'm': 'ɱ', 'n': 'ñ', 'o': 'ö', 'p': 'þ', 'q': 'ǫ', 'r': 'ŕ',
+	's': 'š', 't': 'ţ', 'u': 'û', 'v': 'ṽ', 'w': 'ŵ', 'x': 'ẋ', 'y': 'ý', 'z': 'ž',
+	'A': 'Å', 'B': 'Ɓ', 'C': 'Ç', 'D': 'Ð', 'E': 'É', 'F': 'Ƒ', 'G': 'Ĝ', 'H': 'Ĥ', 'I': 'Î',
+	'J': 'Ĵ', 'K': 'Ķ', 'L': 'Ļ', 'M': 'Ṁ', 'N': 'Ñ', 'O': 'Ö', 'P': 'Þ', 'Q': 'Ǫ', 'R': 'Ŕ',
+	'S': 'Š', 'T': 'Ţ', 'U': 'Û', 'V': 'Ṽ', 'W': 'Ŵ', 'X': 'Ẋ', 'Y': 'Ý', 'Z': 'Ž',
+}
+
+/*
+A pseudo-locale provider. It offers a single language, whose resources are those of the
+source language of the catalog, transformed.
+*/
+type Provider struct {
+	catalog   *ginta.Catalog
+	source    ginta.Locale
+	language  types.Language
+	transform func(string) string
+}
+
+/*
+Returns the accented and expanded pseudo-locale en-XA, derived from a source language of a
+catalog. A nil catalog stands for the ginta.DefaultCatalog.
+*/
+func Accented(c *ginta.Catalog, source ginta.Locale) *Provider {
+	return New(c, source, types.Language{
+		Code:        AccentedCode,
+		DisplayName: "Pseudo (accented)",
+		Direction:   types.LeftToRight,
+	}, Accent)
+}
+
+/*
+Returns the mirrored right-to-left pseudo-locale ar-XB, derived from a source language of a
+catalog. A nil catalog stands for the ginta.DefaultCatalog.
+*/
+func Mirrored(c *ginta.Catalog, source ginta.Locale) *Provider {
+	return New(c, source, types.Language{
+		Code:        MirroredCode,
+		DisplayName: "Pseudo (mirrored)",
+		Direction:   types.RightToLeft,
+	}, Mirror)
+}
+
+/*
+Returns a pseudo-locale with a custom transformation of the values of the source language.
+Transformations should keep placeholders (See Transform). The pseudo-locale falls back to
+the source, unless the language declares another parent.
+*/
+func New(c *ginta.Catalog, source ginta.Locale, language types.Language, transform func(string) string) *Provider {
+	if c == nil {
+		c = ginta.DefaultCatalog
+	}
+	if language.Parent == "" {
+		language.Parent = string(source)
+	}
+
+	return &Provider{c, source, language, transform}
+}
+
+func (p *Provider) Enumerate() <-chan types.Language {
+	c := make(chan types.Language, 1)
+	c <- p.language
+	close(c)
+
+	return c
+}
+
+// Lists the transformed resources of the source language. Resources below "internal" are left out.
+// The source is read as a whole, so that neither its lookup statistics nor recorded usage count
+// the resources as requested
+func (p *Provider) List(code string) <-chan types.Resource {
+	c := make(chan types.Resource)
+
+	go func() {
+		defer close(c)
+		if code != p.language.Code {
+			return
+		}
+
+		flatten(p.catalog.Subtree(p.source, ""), "", func(key, val string) {
+			if !strings.HasPrefix(key, "internal"+types.ResourceKeySegmentSeparator) {
+				c <- types.Resource{key, p.transform(val)}
+			}
+		})
+	}()
+
+	return c
+}
+
+// passes the resources of a tree to a function, by their full key
+func flatten(tree ginta.Tree, prefix string, resource func(key, val string)) {
+	for name, node := range tree {
+		key := prefix
+		if name != "" && prefix != "" {
+			key += types.ResourceKeySegmentSeparator + name
+		} else if name != "" {
+			key = name
+		}
+
+		switch node := node.(type) {
+		case ginta.Tree:
+			flatten(node, key, resource)
+		case string:
+			resource(key, node)
+		}
+	}
+}
+
+// Describes the provider by the pseudo-locale and its source
+func (p *Provider) String() string {
+	return "pseudo:" + p.language.Code + "<" + string(p.source)
+}
+
+/*
+Applies a transformation to the text of a value between its placeholders, keeping the
+placeholders unchanged
+*/
+func Transform(value string, transform func(string) string) string {
+	var result, text bytes.Buffer
+	placeholder := false
+	for _, next := range value {
+		switch {
+		case !placeholder && next == '{':
+			result.WriteString(transform(text.String()))
+			text.Reset()
+			placeholder = true
+		case placeholder && next == '}':
+			placeholder = false
+		case !placeholder:
+			text.WriteRune(next)
+			continue
+		}
+
+		result.WriteRune(next)
+	}
+
+	result.WriteString(transform(text.String()))
+	return result.String()
+}
+
+/*
+Accents the ASCII letters of a value, brackets it and expands it by about 40% of its letters
+with tildes, revealing both hard-coded and truncated strings. Placeholders are kept.
+*/
+func Accent(value string) string {
+	if value == "" {
+		return value
+	}
+
+	letters := 0
+	accented := Transform(value, func(text string) string {
+		runes := []rune(text)
+		for i, r := range runes {
+			if a, ok := accents[r]; ok {
+				runes[i] = a
+			}
+			if unicode.IsLetter(r) {
+				letters++
+			}
+		}
+
+		return string(runes)
+	})
+
+	if padding := (letters*2 + 4) / 5; padding > 0 {
+		accented += " " + strings.Repeat("~", padding)
+	}
+
+	return "[" + accented + "]"
+}
+
+/*
+Mirrors every word of a value by Unicode right-to-left overrides. Placeholders are kept.
+*/
+func Mirror(value string) string {
+	return Transform(value, func(text string) string {
+		var result bytes.Buffer
+		word := false
+		for _, r := range text {
+			if space := unicode.IsSpace(r); space && word {
+				result.WriteRune(popDirection)
+				word = false
+			} else if !space && !word {
+				result.WriteRune(rightToLeftOverride)
+				word = true
+			}
+
+			result.WriteRune(r)
+		}
+
+		if word {
+			result.WriteRune(popDirection)
+		}
+
+		return result.String()
+	})
+}
@@ -0,0 +1,89 @@
+package pseudo
+
+import (
+	"github.com/beatgammit/ginta"
+	types "github.com/beatgammit/ginta/common"
+	format "github.com/beatgammit/ginta/fmt"
+	"github.com/beatgammit/ginta/providers/simple"
+	"testing"
+)
+
+func TestAccent(t *testing.T) {
+	values := map[string]string{
+		"":                   "",
+		"Save":               "[Šàṽé ~~]",
+		"{0} files":          "[{0} ƒîļéš ~~]",
+		"Hi {1,number,%d}!":  "[Ĥî {1,number,%d}! ~]",
+		"{0}":                "[{0}]",
+		"unclosed {0 and on": "[ûñçļöšéð {0 and on ~~~~]",
+	}
+	for value, expected := range values {
+		if accented := Accent(value); accented != expected {
+			t.Errorf("%q: %q", value, accented)
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	if mirrored := Mirror("Open {0} now"); mirrored != "\u202eOpen\u202c {0} \u202enow\u202c" {
+		t.Errorf("%q", mirrored)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	c.Register(simple.New().AddLanguage("en", "English", map[string]string{
+		"greeting":            "Hello {0}",
+		"internal:NativeName": "English",
+	}))
+	c.Register(Accented(c, "en"))
+	c.Register(Mirrored(c, "en"))
+
+	if str, err := c.GetResource(AccentedCode, "greeting"); err != nil || str != "[Ĥéļļö {0} ~~]" {
+		t.Error(str, err)
+	} else if msg, err := format.Compile(str); err != nil || msg.Format(AccentedCode, "you") != "[Ĥéļļö you ~~]" {
+		t.Error(msg, err)
+	}
+
+	// internal resources are not transformed, but inherited from the source
+	if str, err := c.GetResource(AccentedCode, "internal:NativeName"); err != nil || str != "English" {
+		t.Error(str, err)
+	}
+
+	if lang, ok := c.Language(MirroredCode); !ok || lang.Direction != types.RightToLeft {
+		t.Error(lang, ok)
+	}
+
+	if str, err := c.GetResource(MirroredCode, "greeting"); err != nil || str != "\u202eHello\u202c {0}" {
+		t.Errorf("%q %v", str, err)
+	}
+}
+
+func TestSourceUntouched(t *testing.T) {
+	c := ginta.NewCatalog()
+	defer c.Close()
+
+	c.Register(simple.New().AddLanguage("en", "English", map[string]string{
+		"menu":      "Menu",
+		"menu:open": "Open",
+		"dead":      "Dead",
+	}))
+	c.Register(Accented(c, "en"))
+	c.StartRecording()
+
+	if str, err := c.GetResource(AccentedCode, "menu:open"); err != nil || str != "[Öþéñ ~~]" {
+		t.Error(str, err)
+	}
+	if str, err := c.GetResource(AccentedCode, "menu"); err != nil || str != "[Ṁéñû ~~]" {
+		t.Error(str, err)
+	}
+
+	if stats := c.Stats("en"); stats.Hits != 0 || stats.Misses != 0 {
+		t.Error(stats)
+	}
+	if usage := c.Usage("en"); len(usage.Requested) != 0 || len(usage.Untouched) != 3 {
+		t.Error(usage)
+	}
+}
@@ -19,7 +19,8 @@ func New() Provider {
 }
 
 // Adds a language (by means of a key->value map) to the provider, and returns
-// itself for call chaining
+// itself for call chaining. Entries under the internal: keys describe the language
+// further (See common.Language.SetProperty)
 func (p Provider) AddLanguage(code, name string, entries map[string]string) Provider {
 	p[code] = &language{
 		DisplayName: name,
@@ -35,10 +36,13 @@ func (f Provider) Enumerate() <-chan types.Language {
 	go func() {
 		defer close(c)
 		for code, val := range f {
-			c <- types.Language{
-				Code:        code,
-				DisplayName: val.DisplayName,
+			lang := types.Language{Code: code}
+			for key, entry := range val.Entries {
+				lang.SetProperty(key, entry)
 			}
+			lang.DisplayName = val.DisplayName
+
+			c <- lang
 		}
 
 	}()
@@ -0,0 +1,180 @@
+package ginta
+
+import (
+	"sort"
+	"sync"
+)
+
+/*
+	The use of the resources of a locale while recording (See Catalog.StartRecording)
+*/
+type Usage struct {
+	Locale Locale
+	// The keys requested, sorted
+	Requested []string
+	// The keys of the resources returned, sorted. For keys resolved by a parent (See
+	// ResolveResource), this is the key of the parent
+	Resolved []string
+	// The keys requested but not found, sorted
+	Missed []string
+	// The keys of the locale (See Keys) that were never resolved, sorted. A key counts as
+	// resolved when it was returned for this locale, or for a locale falling back to it.
+	// Resources below "internal" are left out
+	Untouched []string
+}
+
+// the keys requested and resolved per locale requested, and the keys used per locale matching,
+// while recording
+type recording struct {
+	lock      sync.Mutex
+	requested map[Locale]map[string]bool
+	resolved  map[Locale]map[string]bool
+	used      map[Locale]map[string]bool
+}
+
+func (r *recording) record(code, key, matchCode, match string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	l := Locale(code)
+	if r.requested[l] == nil {
+		r.requested[l] = make(map[string]bool)
+		r.resolved[l] = make(map[string]bool)
+	}
+
+	r.requested[l][key] = true
+	if match != "" {
+		r.resolved[l][match] = true
+		if r.used[Locale(matchCode)] == nil {
+			r.used[Locale(matchCode)] = make(map[string]bool)
+		}
+		r.used[Locale(matchCode)][match] = true
+	} else if _, ok := r.resolved[l][key]; !ok {
+		// a miss, unless the key is resolved at another time
+		r.resolved[l][key] = false
+	}
+}
+
+/*
+	Starts recording the resources requested from the DefaultCatalog. See Catalog.StartRecording
+*/
+func StartRecording() {
+	DefaultCatalog.StartRecording()
+}
+
+/*
+	Stops recording the resources requested from the DefaultCatalog
+*/
+func StopRecording() {
+	DefaultCatalog.StopRecording()
+}
+
+/*
+	Returns the use of the resources of this locale since recording started. See Catalog.Usage
+*/
+func (l Locale) Usage() Usage {
+	return DefaultCatalog.Usage(l)
+}
+
+/*
+	Starts recording the resources requested from the catalog by GetResource and ResolveResource,
+	discarding what was recorded before. Recording costs a lock per lookup, and is meant for test
+	runs and sampling windows in production:
+
+		c.StartRecording()
+		runTests()
+		c.StopRecording()
+		for _, usage := range c.Usages() {
+			log.Printf("%s: %d keys never used: %v", usage.Locale, len(usage.Untouched), usage.Untouched)
+		}
+*/
+func (c *Catalog) StartRecording() {
+	r := &recording{
+		requested: make(map[Locale]map[string]bool),
+		resolved:  make(map[Locale]map[string]bool),
+		used:      make(map[Locale]map[string]bool),
+	}
+
+	c.recordingLock.Lock()
+	defer c.recordingLock.Unlock()
+
+	c.recording = r
+	c.universe.SetRecorder(r.record)
+}
+
+/*
+	Stops recording. What was recorded so far remains available (See Usage)
+*/
+func (c *Catalog) StopRecording() {
+	c.universe.SetRecorder(nil)
+}
+
+/*
+	Returns the use of the resources of a locale while recording, compared against the keys of
+	the locale
+*/
+func (c *Catalog) Usage(l Locale) Usage {
+	l = l.Canonical()
+	usage := Usage{l, []string{}, []string{}, []string{}, []string{}}
+
+	c.recordingLock.Lock()
+	r := c.recording
+	c.recordingLock.Unlock()
+
+	resolved := make(map[string]bool)
+	if r != nil {
+		r.lock.Lock()
+		for key := range r.requested[l] {
+			usage.Requested = append(usage.Requested, key)
+		}
+		for key, found := range r.resolved[l] {
+			resolved[key] = found
+			if found {
+				usage.Resolved = append(usage.Resolved, key)
+			} else {
+				usage.Missed = append(usage.Missed, key)
+			}
+		}
+		for key := range r.used[l] {
+			resolved[key] = true
+		}
+		r.lock.Unlock()
+	}
+
+	for _, key := range c.Keys(l, "") {
+		if !resolved[key] && covered(key, "") {
+			usage.Untouched = append(usage.Untouched, key)
+		}
+	}
+
+	sort.Strings(usage.Requested)
+	sort.Strings(usage.Resolved)
+	sort.Strings(usage.Missed)
+	return usage
+}
+
+/*
+	Returns the use of the resources of every locale requested while recording, sorted by locale
+*/
+func (c *Catalog) Usages() []Usage {
+	c.recordingLock.Lock()
+	r := c.recording
+	c.recordingLock.Unlock()
+
+	locales := []string{}
+	if r != nil {
+		r.lock.Lock()
+		for l := range r.requested {
+			locales = append(locales, string(l))
+		}
+		r.lock.Unlock()
+	}
+
+	sort.Strings(locales)
+	usages := make([]Usage, len(locales))
+	for i, l := range locales {
+		usages[i] = c.Usage(Locale(l))
+	}
+
+	return usages
+}
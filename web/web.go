@@ -0,0 +1,223 @@
+/*
+net/http integration of ginta. The Handler middleware negotiates the locale of each request,
+and stores it in the request context, from where it can be retrieved with ginta.LocaleFromContext.
+
+The locale is selected from a configurable, ordered list of sources. The first source that
+names a locale supported by the matcher wins. If no source yields a supported locale, the
+default locale of the matcher is used.
+
+Example:
+	m := match.New("en", "de", "fr")
+	http.Handle("/", web.Handler(app, m, web.PathPrefix(), web.Cookie("lang"), web.AcceptLanguage()))
+
+	func app(w http.ResponseWriter, r *http.Request) {
+		res := fmt.NewResolver(ginta.LocaleFromContext(r.Context()), "pages:home")
+		...
+	}
+*/
+package web
+
+import (
+	"github.com/beatgammit/ginta"
+	"github.com/beatgammit/ginta/match"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Header listing the languages of the response
+	ContentLanguageHeader = "Content-Language"
+	// Header of the client's language preferences
+	AcceptLanguageHeader = "Accept-Language"
+	// Header listing the request headers the response depends on
+	VaryHeader = "Vary"
+	// Name of the request header carrying cookies
+	CookieHeader = "Cookie"
+	// Name of the cookie and query parameter used by the default sources
+	DefaultParameter = "lang"
+)
+
+/*
+A source of locale preferences of a request
+*/
+type Source interface {
+	// Returns the locale preferences stated in the request, by descending quality
+	Preferences(r *http.Request) []match.Preference
+	// Returns the request header the preferences are read from, or "" if they do not depend on a header
+	Header() string
+}
+
+/*
+Sources that encode the locale in the request URL may implement this interface to remove
+it, once it has been selected. The returned request is passed on to the wrapped handler.
+*/
+type Stripper interface {
+	Strip(r *http.Request) *http.Request
+}
+
+/*
+Wraps the handler with locale negotiation. The sources are consulted in order, and the first
+supported locale is stored in the request context. Without sources, the query parameter and
+cookie named DefaultParameter are consulted, followed by the Accept-Language header. If the matcher
+is nil, the languages registered with ginta at the time of the request are supported (See
+match.Registered).
+
+The response carries the negotiated locale in its Content-Language header, and all headers
+consulted by the sources in its Vary header.
+*/
+func Handler(next http.Handler, m *match.Matcher, sources ...Source) http.Handler {
+	if len(sources) == 0 {
+		sources = []Source{Query(DefaultParameter), Cookie(DefaultParameter), AcceptLanguage()}
+	}
+
+	vary := []string{}
+	for _, source := range sources {
+		if header := source.Header(); header != "" {
+			vary = append(vary, header)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		matcher := m
+		if matcher == nil {
+			matcher = match.Registered()
+		}
+
+		locale, r := negotiate(matcher, sources, r)
+
+		if len(vary) > 0 {
+			w.Header().Add(VaryHeader, strings.Join(vary, ", "))
+		}
+		w.Header().Set(ContentLanguageHeader, string(locale))
+
+		next.ServeHTTP(w, r.WithContext(ginta.NewContext(r.Context(), locale)))
+	})
+}
+
+func negotiate(m *match.Matcher, sources []Source, r *http.Request) (ginta.Locale, *http.Request) {
+	for _, source := range sources {
+		if prefs := source.Preferences(r); len(prefs) > 0 {
+			if locale, confidence := m.Match(prefs...); confidence > match.No {
+				if stripper, ok := source.(Stripper); ok {
+					r = stripper.Strip(r)
+				}
+
+				return locale, r
+			}
+		}
+	}
+
+	locale, _ := m.Match()
+	return locale, r
+}
+
+type acceptLanguage int
+
+/*
+Reads the preferences from the Accept-Language header
+*/
+func AcceptLanguage() Source {
+	return acceptLanguage(0)
+}
+
+func (_ acceptLanguage) Preferences(r *http.Request) []match.Preference {
+	prefs, _ := match.ParseAcceptLanguage(r.Header.Get(AcceptLanguageHeader))
+	return prefs
+}
+
+func (_ acceptLanguage) Header() string {
+	return AcceptLanguageHeader
+}
+
+type cookie string
+
+/*
+Reads the locale from the value of a cookie
+*/
+func Cookie(name string) Source {
+	return cookie(name)
+}
+
+func (c cookie) Preferences(r *http.Request) []match.Preference {
+	if value, err := r.Cookie(string(c)); err == nil {
+		return single(value.Value)
+	}
+
+	return nil
+}
+
+func (_ cookie) Header() string {
+	return CookieHeader
+}
+
+type query string
+
+/*
+Reads the locale from a query parameter of the request URL
+*/
+func Query(parameter string) Source {
+	return query(parameter)
+}
+
+func (q query) Preferences(r *http.Request) []match.Preference {
+	return single(r.URL.Query().Get(string(q)))
+}
+
+func (_ query) Header() string {
+	return ""
+}
+
+type pathPrefix int
+
+/*
+Reads the locale from the first segment of the request path (/de-AT/some/page). If the
+locale is selected, the segment is removed from the path passed to the wrapped handler.
+*/
+func PathPrefix() Source {
+	return pathPrefix(0)
+}
+
+func (_ pathPrefix) Preferences(r *http.Request) []match.Preference {
+	segment, _ := splitPath(r.URL.Path)
+	return single(segment)
+}
+
+func (_ pathPrefix) Header() string {
+	return ""
+}
+
+func (_ pathPrefix) Strip(r *http.Request) *http.Request {
+	stripped := new(http.Request)
+	*stripped = *r
+
+	url := *r.URL
+	_, url.Path = splitPath(r.URL.Path)
+	url.RawPath = ""
+	stripped.URL = &url
+
+	return stripped
+}
+
+// splits a path into its first segment and the remaining path
+func splitPath(path string) (string, string) {
+	path = strings.TrimPrefix(path, "/")
+	if idx := strings.Index(path, "/"); idx > -1 {
+		return path[:idx], path[idx:]
+	}
+
+	return path, "/"
+}
+
+// turns a single locale code into a preference list
+func single(code string) []match.Preference {
+	if code = strings.TrimSpace(code); code == "" {
+		return nil
+	}
+
+	l := ginta.Locale(code)
+	if _, err := l.Tag(); err != nil {
+		return nil
+	}
+
+	return []match.Preference{{l.Canonical(), 1}}
+}
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"github.com/beatgammit/ginta"
+	"github.com/beatgammit/ginta/match"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recorder struct {
+	locale ginta.Locale
+	path   string
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.locale = ginta.LocaleFromContext(r.Context())
+	rec.path = r.URL.Path
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestDefaultSources(t *testing.T) {
+	rec := new(recorder)
+	h := Handler(rec, match.New("en", "de", "fr"))
+
+	r := httptest.NewRequest("GET", "/page?lang=fr", nil)
+	r.AddCookie(&http.Cookie{Name: DefaultParameter, Value: "de"})
+	r.Header.Set(AcceptLanguageHeader, "de")
+
+	w := serve(h, r)
+	if rec.locale != "fr" || w.Header().Get(ContentLanguageHeader) != "fr" {
+		t.Error(rec.locale, w.Header())
+	}
+
+	if vary := w.Header().Get(VaryHeader); vary != CookieHeader+", "+AcceptLanguageHeader {
+		t.Error(vary)
+	}
+
+	r = httptest.NewRequest("GET", "/page?lang=xx", nil)
+	r.AddCookie(&http.Cookie{Name: DefaultParameter, Value: "de"})
+	serve(h, r)
+	if rec.locale != "de" {
+		t.Error(rec.locale)
+	}
+
+	r = httptest.NewRequest("GET", "/page", nil)
+	r.Header.Set(AcceptLanguageHeader, "it, fr-CA;q=0.8")
+	serve(h, r)
+	if rec.locale != "fr" {
+		t.Error(rec.locale)
+	}
+
+	serve(h, httptest.NewRequest("GET", "/page", nil))
+	if rec.locale != "en" {
+		t.Error(rec.locale)
+	}
+}
+
+func TestPathPrefix(t *testing.T) {
+	rec := new(recorder)
+	h := Handler(rec, match.New("en", "de-AT"), PathPrefix(), AcceptLanguage())
+
+	serve(h, httptest.NewRequest("GET", "/de-at/some/page", nil))
+	if rec.locale != "de-AT" || rec.path != "/some/page" {
+		t.Error(rec.locale, rec.path)
+	}
+
+	r := httptest.NewRequest("GET", "/api/page", nil)
+	r.Header.Set(AcceptLanguageHeader, "de")
+	serve(h, r)
+	if rec.locale != "de-AT" || rec.path != "/api/page" {
+		t.Error(rec.locale, rec.path)
+	}
+
+	serve(h, httptest.NewRequest("GET", "/en", nil))
+	if rec.locale != "en" || rec.path != "/" {
+		t.Error(rec.locale, rec.path)
+	}
+}